@@ -0,0 +1,111 @@
+package ciphers
+
+import (
+	"encoding/hex"
+	"strconv"
+)
+
+// cipherNames maps the known cipher suites to their OpenSSL names
+var cipherNames = map[string]string{
+	"0004": "RC4-MD5",
+	"0005": "RC4-SHA",
+	"0007": "IDEA-CBC-SHA",
+	"000a": "DES-CBC3-SHA",
+	"0016": "DHE-RSA-DES-CBC3-SHA",
+	"002f": "AES128-SHA",
+	"0033": "DHE-RSA-AES128-SHA",
+	"0035": "AES256-SHA",
+	"0039": "DHE-RSA-AES256-SHA",
+	"003c": "AES128-SHA256",
+	"003d": "AES256-SHA256",
+	"0041": "CAMELLIA128-SHA",
+	"0045": "DHE-RSA-CAMELLIA128-SHA",
+	"0067": "DHE-RSA-AES128-SHA256",
+	"006b": "DHE-RSA-AES256-SHA256",
+	"0084": "CAMELLIA256-SHA",
+	"0088": "DHE-RSA-CAMELLIA256-SHA",
+	"009a": "DHE-RSA-SEED-SHA",
+	"009c": "AES128-GCM-SHA256",
+	"009d": "AES256-GCM-SHA384",
+	"009e": "DHE-RSA-AES128-GCM-SHA256",
+	"009f": "DHE-RSA-AES256-GCM-SHA384",
+	"00ba": "CAMELLIA128-SHA256",
+	"00be": "DHE-RSA-CAMELLIA128-SHA256",
+	"00c0": "CAMELLIA256-SHA256",
+	"00c4": "DHE-RSA-CAMELLIA256-SHA256",
+	"c007": "ECDHE-ECDSA-RC4-SHA",
+	"c008": "ECDHE-ECDSA-DES-CBC3-SHA",
+	"c009": "ECDHE-ECDSA-AES128-SHA",
+	"c00a": "ECDHE-ECDSA-AES256-SHA",
+	"c011": "ECDHE-RSA-RC4-SHA",
+	"c012": "ECDHE-RSA-DES-CBC3-SHA",
+	"c013": "ECDHE-RSA-AES128-SHA",
+	"c014": "ECDHE-RSA-AES256-SHA",
+	"c023": "ECDHE-ECDSA-AES128-SHA256",
+	"c024": "ECDHE-ECDSA-AES256-SHA384",
+	"c027": "ECDHE-RSA-AES128-SHA256",
+	"c028": "ECDHE-RSA-AES256-SHA384",
+	"c02b": "ECDHE-ECDSA-AES128-GCM-SHA256",
+	"c02c": "ECDHE-ECDSA-AES256-GCM-SHA384",
+	"c02f": "ECDHE-RSA-AES128-GCM-SHA256",
+	"c030": "ECDHE-RSA-AES256-GCM-SHA384",
+	"c060": "ECDHE-ARIA128-GCM-SHA256",
+	"c061": "ECDHE-ARIA256-GCM-SHA384",
+	"c072": "ECDHE-ECDSA-CAMELLIA128-SHA256",
+	"c073": "ECDHE-ECDSA-CAMELLIA256-SHA384",
+	"c076": "ECDHE-RSA-CAMELLIA128-SHA256",
+	"c077": "ECDHE-RSA-CAMELLIA256-SHA384",
+	"c09c": "AES128-CCM",
+	"c09d": "AES256-CCM",
+	"c09e": "DHE-RSA-AES128-CCM",
+	"c09f": "DHE-RSA-AES256-CCM",
+	"c0a0": "AES128-CCM8",
+	"c0a1": "AES256-CCM8",
+	"c0a2": "DHE-RSA-AES128-CCM8",
+	"c0a3": "DHE-RSA-AES256-CCM8",
+	"c0ac": "ECDHE-ECDSA-AES128-CCM",
+	"c0ad": "ECDHE-ECDSA-AES256-CCM",
+	"c0ae": "ECDHE-ECDSA-AES128-CCM8",
+	"c0af": "ECDHE-ECDSA-AES256-CCM8",
+	"cc13": "ECDHE-RSA-CHACHA20-POLY1305-OLD",
+	"cc14": "ECDHE-ECDSA-CHACHA20-POLY1305-OLD",
+	"cca8": "ECDHE-RSA-CHACHA20-POLY1305",
+	"cca9": "ECDHE-ECDSA-CHACHA20-POLY1305",
+	"1301": "TLS_AES_128_GCM_SHA256",
+	"1302": "TLS_AES_256_GCM_SHA384",
+	"1303": "TLS_CHACHA20_POLY1305_SHA256",
+	"1304": "TLS_AES_128_CCM_SHA256",
+	"1305": "TLS_AES_128_CCM_8_SHA256",
+}
+
+// versionNames maps the version characters of a JARM hash to protocol names
+var versionNames = map[byte]string{
+	'a': "SSL 3.0",
+	'b': "TLS 1.0",
+	'c': "TLS 1.1",
+	'd': "TLS 1.2",
+	'e': "TLS 1.3",
+}
+
+// CipherFromIndex returns the cipher suite for an index produced by ExtractCipherBytes
+func CipherFromIndex(idx string) ([]byte, bool) {
+	i, err := strconv.ParseUint(idx, 16, 8)
+	if err != nil || i == 0 || int(i) > len(cipherListOrder) {
+		return nil, false
+	}
+	return cipherListOrder[i-1], true
+}
+
+// CipherName returns the OpenSSL name of a cipher suite, or its hex value if unknown
+func CipherName(cipher []byte) string {
+	c := hex.EncodeToString(cipher)
+	if name, ok := cipherNames[c]; ok {
+		return name
+	}
+	return c
+}
+
+// VersionName returns the protocol name for a version byte produced by ExtractVersionByte
+func VersionName(v byte) string {
+	return versionNames[v]
+}
@@ -0,0 +1,130 @@
+package gojarm
+
+import (
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/TheGejr/gojarm/jarm"
+)
+
+// ClusterMode selects how results are grouped
+type ClusterMode string
+
+const (
+	// ClusterByHash groups results with identical hashes
+	ClusterByHash ClusterMode = "HASH"
+	// ClusterByPrefix groups results sharing the cipher and version prefix
+	ClusterByPrefix ClusterMode = "PREFIX"
+	// ClusterByDigest groups results sharing the extension digest
+	ClusterByDigest ClusterMode = "DIGEST"
+	// ClusterByDistance groups results within a distance of the first hash of a cluster
+	ClusterByDistance ClusterMode = "DISTANCE"
+)
+
+// ClusterOptions specifies how results are clustered
+type ClusterOptions struct {
+	Mode ClusterMode
+	// MaxDistance is the largest number of differing components within a cluster, used by ClusterByDistance
+	MaxDistance int
+	// Representatives is the number of hosts kept per cluster, defaults to 5
+	Representatives int
+	// SkipZero leaves out failed results and results with an empty hash, prefixed or not
+	SkipZero bool
+}
+
+// Cluster summarizes a group of results
+type Cluster struct {
+	// Key is the shared hash, prefix or digest, or the first hash of a distance cluster
+	Key    string
+	Count  int
+	Hashes []string
+	Hosts  []string
+}
+
+// ClusterResults groups results and returns the clusters ordered by size
+//
+// Prefixed hashes, such as DTLS and QUIC hashes, are only clustered with hashes of the
+// same prefix. Hashes that cannot be split into components, such as legacy
+// hashes, are grouped by the exact hash in every mode.
+func ClusterResults(results []Result, opts ClusterOptions) []Cluster {
+	if opts.Representatives <= 0 {
+		opts.Representatives = 5
+	}
+
+	clusters := []*Cluster{}
+	byKey := map[string]*Cluster{}
+
+	for _, r := range results {
+		prefix, bare := jarm.SplitPrefix(r.Hash)
+		if opts.SkipZero && (r.Error != nil || strings.Trim(bare, "0") == "") {
+			continue
+		}
+
+		key := ""
+		switch {
+		case len(bare) != jarm.HashLength:
+			key = r.Hash
+		case opts.Mode == ClusterByPrefix:
+			key = prefix + bare[:jarm.PrefixLength]
+		case opts.Mode == ClusterByDigest:
+			key = prefix + bare[jarm.PrefixLength:]
+		case opts.Mode == ClusterByDistance:
+			key = nearestCluster(clusters, r.Hash, opts.MaxDistance)
+		default:
+			key = r.Hash
+		}
+
+		c, ok := byKey[key]
+		if !ok {
+			c = &Cluster{Key: key, Hashes: []string{}, Hosts: []string{}}
+			byKey[key] = c
+			clusters = append(clusters, c)
+		}
+
+		c.Count++
+		if !contains(c.Hashes, r.Hash) {
+			c.Hashes = append(c.Hashes, r.Hash)
+		}
+		if len(c.Hosts) < opts.Representatives {
+			c.Hosts = append(c.Hosts, net.JoinHostPort(r.Target.Host, strconv.Itoa(r.Target.Port)))
+		}
+	}
+
+	sort.SliceStable(clusters, func(i, j int) bool {
+		return clusters[i].Count > clusters[j].Count
+	})
+
+	summaries := []Cluster{}
+	for _, c := range clusters {
+		summaries = append(summaries, *c)
+	}
+	return summaries
+}
+
+// nearestCluster returns the key of the closest cluster within the distance, or the hash itself
+func nearestCluster(clusters []*Cluster, hash string, maxDistance int) string {
+	best := hash
+	bestDistance := maxDistance + 1
+	for _, c := range clusters {
+		sim, err := jarm.Compare(c.Key, hash)
+		if err != nil {
+			continue
+		}
+		if sim.Distance < bestDistance {
+			best = c.Key
+			bestDistance = sim.Distance
+		}
+	}
+	return best
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
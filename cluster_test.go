@@ -0,0 +1,84 @@
+package gojarm
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/TheGejr/gojarm/jarm"
+)
+
+func TestClusterResultsPrefixes(t *testing.T) {
+	const (
+		tlsA = "29d29d00029d29d00041d43d00041d2aa5ce6a70de7ba95aef77a77b00a0af"
+		tlsB = "29d29d00029d29d00041d41d00041d2aa5ce6a70de7ba95aef77a77b00a0af"
+	)
+	legacy := jarm.LegacyPrefix + "04c04c04c04c04s" + "00" + tlsA[30:]
+
+	results := []Result{
+		{Target: Target{Host: "a", Port: 443}, Hash: tlsA},
+		{Target: Target{Host: "b", Port: 443}, Hash: tlsB},
+		{Target: Target{Host: "c", Port: 443}, Hash: jarm.DTLSPrefix + tlsA},
+		{Target: Target{Host: "d", Port: 443}, Hash: jarm.DTLSPrefix + tlsA},
+		{Target: Target{Host: "e", Port: 443}, Hash: jarm.DTLSPrefix + ZeroHash},
+		{Target: Target{Host: "f", Port: 443}, Hash: ZeroHash},
+		{Target: Target{Host: "g", Port: 443}, Hash: legacy},
+		{Target: Target{Host: "i", Port: 443}, Hash: jarm.QUICPrefix + tlsA},
+		{Target: Target{Host: "j", Port: 443}, Hash: jarm.QUICPrefix + ZeroHash},
+		{Target: Target{Host: "h", Port: 443}, Error: errors.New("failed")},
+	}
+
+	tests := []struct {
+		mode ClusterMode
+		keys map[string]int
+	}{
+		{ClusterByHash, map[string]int{
+			tlsA:                   1,
+			tlsB:                   1,
+			jarm.DTLSPrefix + tlsA: 2,
+			jarm.QUICPrefix + tlsA: 1,
+			legacy:                 1,
+		}},
+		{ClusterByPrefix, map[string]int{
+			tlsA[:30]:                   1,
+			tlsB[:30]:                   1,
+			jarm.DTLSPrefix + tlsA[:30]: 2,
+			jarm.QUICPrefix + tlsA[:30]: 1,
+			legacy:                      1,
+		}},
+		{ClusterByDigest, map[string]int{
+			tlsA[30:]:                   2,
+			jarm.DTLSPrefix + tlsA[30:]: 2,
+			jarm.QUICPrefix + tlsA[30:]: 1,
+			legacy:                      1,
+		}},
+		{ClusterByDistance, map[string]int{
+			tlsA:                   2,
+			jarm.DTLSPrefix + tlsA: 2,
+			jarm.QUICPrefix + tlsA: 1,
+			legacy:                 1,
+		}},
+	}
+
+	for _, tt := range tests {
+		clusters := ClusterResults(results, ClusterOptions{Mode: tt.mode, MaxDistance: 2, SkipZero: true})
+		if len(clusters) != len(tt.keys) {
+			t.Errorf("%s: got %d clusters, want %d: %+v", tt.mode, len(clusters), len(tt.keys), clusters)
+			continue
+		}
+		for _, c := range clusters {
+			if tt.keys[c.Key] != c.Count {
+				t.Errorf("%s: cluster %s has %d results, want %d", tt.mode, c.Key, c.Count, tt.keys[c.Key])
+			}
+		}
+	}
+
+	// Without SkipZero the zero hashes of each transport form their own clusters
+	clusters := ClusterResults(results, ClusterOptions{Mode: ClusterByHash})
+	found := map[string]bool{}
+	for _, c := range clusters {
+		found[c.Key] = true
+	}
+	if !found[ZeroHash] || !found[jarm.DTLSPrefix+ZeroHash] || !found[jarm.QUICPrefix+ZeroHash] {
+		t.Errorf("zero hashes not clustered per transport: %+v", clusters)
+	}
+}
@@ -0,0 +1,27 @@
+package gojarm
+
+import (
+	"net"
+	"time"
+)
+
+// datagramTimeout is the time to wait for a reply before a datagram is retransmitted
+var datagramTimeout = time.Second * 2
+
+// datagramRetransmits is the number of times an unanswered datagram is sent again
+const datagramRetransmits = 1
+
+// exchangeDatagram sends a datagram and returns the reply, retransmitting it if none arrives in time
+func exchangeDatagram(conn net.Conn, datagram []byte) []byte {
+	buff := make([]byte, 65535)
+	for n := 0; n <= datagramRetransmits; n++ {
+		conn.SetDeadline(time.Now().Add(datagramTimeout))
+		if _, err := conn.Write(datagram); err != nil {
+			return nil
+		}
+		if l, err := conn.Read(buff); err == nil {
+			return buff[:l]
+		}
+	}
+	return nil
+}
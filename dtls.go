@@ -0,0 +1,65 @@
+package gojarm
+
+import (
+	"github.com/TheGejr/gojarm/dtls"
+	"github.com/TheGejr/gojarm/handshake"
+	"github.com/TheGejr/gojarm/models"
+	"github.com/TheGejr/gojarm/probes"
+	"github.com/TheGejr/gojarm/protocols"
+)
+
+// TransportDTLS selects DTLS over UDP for a target
+const TransportDTLS = models.TransportDTLS
+
+// probeDTLS sends a single DTLS probe to the target and parses the response
+//
+// A HelloVerifyRequest is answered by resending the client hello with the
+// cookie, and the reply to that is used as the response of the probe.
+func probeDTLS(t Target, probe models.JarmOptions) (ProbeResult, error) {
+	pr := ProbeResult{Probe: probe}
+
+	conn, err := dialTarget(t)
+	if err != nil {
+		return pr, err
+	}
+	defer conn.Close()
+
+	data := probes.BuildProbe(probe)
+	sessionID := dtlsSessionID(data)
+
+	reply := exchangeDatagram(conn, data)
+	if cookie, ok := dtls.HelloVerifyRequest(reply); ok {
+		retry, err := dtls.WithCookie(data, cookie)
+		if err != nil {
+			return pr, nil
+		}
+		reply = exchangeDatagram(conn, retry)
+	}
+
+	pr.Response = reply
+	if len(reply) > 0 {
+		pr.Protocol = protocols.Identify(reply)
+	}
+
+	// JA3S and JA4S are computed on the DTLS versions
+	var sh *handshake.ServerHello
+	if body, ok := dtls.ServerHelloBody(reply); ok {
+		sh, _ = handshake.ParseServerHelloBody(body)
+	}
+	fingerprintResponse(&pr, t, dtls.ToTLS(reply), sessionID, 'd', sh)
+
+	return pr, nil
+}
+
+// dtlsSessionID returns the session ID of a client hello datagram built by probes.BuildProbe
+func dtlsSessionID(datagram []byte) []byte {
+	// record header (13), handshake header (12), version (2) and random (32)
+	if len(datagram) < 60 {
+		return nil
+	}
+	l := int(datagram[59])
+	if len(datagram) < 60+l {
+		return nil
+	}
+	return datagram[60 : 60+l]
+}
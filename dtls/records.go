@@ -0,0 +1,234 @@
+package dtls
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// DTLS protocol versions
+const (
+	VersionDTLS10 = 0xfeff
+	VersionDTLS12 = 0xfefd
+	VersionDTLS13 = 0xfefc
+)
+
+// Record and handshake types
+const (
+	recordTypeAlert     = 21
+	recordTypeHandshake = 22
+
+	typeClientHello        = 1
+	typeServerHello        = 2
+	typeHelloVerifyRequest = 3
+
+	// record header: type, version, epoch, sequence number and length
+	recordHeaderLength = 13
+	// handshake header: type, length, message sequence, fragment offset and fragment length
+	handshakeHeaderLength = 12
+
+	extSupportedVersions = 0x002b
+)
+
+// Record is a single DTLS record
+type Record struct {
+	Type     byte
+	Version  uint16
+	Epoch    uint16
+	Sequence uint64
+	Fragment []byte
+}
+
+// TLSVersion returns the TLS version a DTLS version is derived from, or the version itself if it is not a DTLS version
+func TLSVersion(v uint16) uint16 {
+	switch v {
+	case VersionDTLS10:
+		return 0x0302
+	case VersionDTLS12:
+		return 0x0303
+	case VersionDTLS13:
+		return 0x0304
+	}
+	return v
+}
+
+// ParseRecords parses the records of a datagram, ignoring a truncated trailing record
+func ParseRecords(data []byte) []Record {
+	records := []Record{}
+	for len(data) >= recordHeaderLength {
+		length := int(binary.BigEndian.Uint16(data[11:13]))
+		if len(data) < recordHeaderLength+length {
+			break
+		}
+		records = append(records, Record{
+			Type:     data[0],
+			Version:  binary.BigEndian.Uint16(data[1:3]),
+			Epoch:    binary.BigEndian.Uint16(data[3:5]),
+			Sequence: uint64(binary.BigEndian.Uint16(data[5:7]))<<32 | uint64(binary.BigEndian.Uint32(data[7:11])),
+			Fragment: data[recordHeaderLength : recordHeaderLength+length],
+		})
+		data = data[recordHeaderLength+length:]
+	}
+	return records
+}
+
+// EncodeRecord returns a DTLS record with the given header fields
+func EncodeRecord(r Record) []byte {
+	out := []byte{r.Type, byte(r.Version >> 8), byte(r.Version), byte(r.Epoch >> 8), byte(r.Epoch)}
+	out = append(out, byte(r.Sequence>>40), byte(r.Sequence>>32), byte(r.Sequence>>24), byte(r.Sequence>>16), byte(r.Sequence>>8), byte(r.Sequence))
+	out = append(out, byte(len(r.Fragment)>>8), byte(len(r.Fragment)))
+	return append(out, r.Fragment...)
+}
+
+// EncodeHandshake returns an unfragmented handshake message
+func EncodeHandshake(msgType byte, messageSeq uint16, body []byte) []byte {
+	l := len(body)
+	out := []byte{msgType, byte(l >> 16), byte(l >> 8), byte(l)}
+	out = append(out, byte(messageSeq>>8), byte(messageSeq))
+	out = append(out, 0, 0, 0)
+	out = append(out, byte(l>>16), byte(l>>8), byte(l))
+	return append(out, body...)
+}
+
+// handshakeMessage reassembles the first handshake message of a type from the records of a datagram
+func handshakeMessage(data []byte, msgType byte) ([]byte, bool) {
+	var body []byte
+	received := 0
+	for _, r := range ParseRecords(data) {
+		if r.Type != recordTypeHandshake || r.Epoch != 0 {
+			continue
+		}
+		fragments := r.Fragment
+		for len(fragments) >= handshakeHeaderLength {
+			h := fragments[:handshakeHeaderLength]
+			length := int(h[1])<<16 | int(h[2])<<8 | int(h[3])
+			offset := int(h[6])<<16 | int(h[7])<<8 | int(h[8])
+			fragLength := int(h[9])<<16 | int(h[10])<<8 | int(h[11])
+			if len(fragments) < handshakeHeaderLength+fragLength {
+				break
+			}
+			fragment := fragments[handshakeHeaderLength : handshakeHeaderLength+fragLength]
+			fragments = fragments[handshakeHeaderLength+fragLength:]
+
+			if h[0] != msgType || offset+fragLength > length {
+				continue
+			}
+			if body == nil {
+				body = make([]byte, length)
+			}
+			// Only contiguous fragments are reassembled
+			if len(body) == length && offset == received {
+				copy(body[offset:], fragment)
+				received += fragLength
+			}
+			if body != nil && received == len(body) {
+				return body, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// HelloVerifyRequest returns the cookie of a HelloVerifyRequest in a datagram
+func HelloVerifyRequest(data []byte) ([]byte, bool) {
+	body, ok := handshakeMessage(data, typeHelloVerifyRequest)
+	if !ok || len(body) < 3 || len(body) < 3+int(body[2]) {
+		return nil, false
+	}
+	return body[3 : 3+int(body[2])], true
+}
+
+// ServerHelloBody returns the server hello message in a datagram, without its headers
+func ServerHelloBody(data []byte) ([]byte, bool) {
+	return handshakeMessage(data, typeServerHello)
+}
+
+// WithCookie returns a client hello datagram built by probes.BuildProbe, resent with a cookie
+//
+// The record sequence number and message sequence are both incremented, as
+// required for the second client hello of a handshake.
+func WithCookie(datagram []byte, cookie []byte) ([]byte, error) {
+	records := ParseRecords(datagram)
+	if len(records) != 1 || records[0].Type != recordTypeHandshake {
+		return nil, errors.New("dtls: not a client hello record")
+	}
+	r := records[0]
+	if len(r.Fragment) < handshakeHeaderLength || r.Fragment[0] != typeClientHello {
+		return nil, errors.New("dtls: not a client hello")
+	}
+	messageSeq := binary.BigEndian.Uint16(r.Fragment[4:6])
+	hello := r.Fragment[handshakeHeaderLength:]
+
+	// version (2) and random (32), followed by the session ID and cookie
+	if len(hello) < 35 || len(hello) < 36+int(hello[34]) {
+		return nil, errors.New("dtls: client hello too short")
+	}
+	cookieOffset := 35 + int(hello[34])
+	rest := hello[cookieOffset+1+int(hello[cookieOffset]):]
+
+	body := append([]byte{}, hello[:cookieOffset]...)
+	body = append(body, byte(len(cookie)))
+	body = append(body, cookie...)
+	body = append(body, rest...)
+
+	r.Sequence++
+	r.Fragment = EncodeHandshake(typeClientHello, messageSeq+1, body)
+	return EncodeRecord(r), nil
+}
+
+// ToTLS returns a TLS view of the first server hello or alert in a datagram
+//
+// Server hellos are converted to a TLS 1.2 handshake record with their DTLS
+// versions replaced by the TLS versions they are derived from, so the TLS
+// parsers and the JARM version alphabet apply. Nil is returned for
+// datagrams holding neither.
+func ToTLS(data []byte) []byte {
+	if body, ok := ServerHelloBody(data); ok && len(body) >= 2 {
+		body = append([]byte{}, body...)
+		binary.BigEndian.PutUint16(body[0:2], TLSVersion(binary.BigEndian.Uint16(body[0:2])))
+		mapSupportedVersion(body)
+
+		message := []byte{typeServerHello, byte(len(body) >> 16), byte(len(body) >> 8), byte(len(body))}
+		message = append(message, body...)
+		out := []byte{recordTypeHandshake, 0x03, 0x03, byte(len(message) >> 8), byte(len(message))}
+		return append(out, message...)
+	}
+
+	for _, r := range ParseRecords(data) {
+		if r.Type == recordTypeAlert && r.Epoch == 0 && len(r.Fragment) >= 2 {
+			return []byte{recordTypeAlert, 0x03, 0x03, 0x00, 0x02, r.Fragment[0], r.Fragment[1]}
+		}
+	}
+	return nil
+}
+
+// mapSupportedVersion replaces the DTLS version of a server hello's supported_versions extension in place
+func mapSupportedVersion(body []byte) {
+	// version, random and session ID
+	if len(body) < 35 {
+		return
+	}
+	offset := 35 + int(body[34])
+	// cipher suite, compression method and extensions length
+	offset += 3
+	if len(body) < offset+2 {
+		return
+	}
+	end := offset + 2 + int(binary.BigEndian.Uint16(body[offset:offset+2]))
+	if end > len(body) {
+		end = len(body)
+	}
+	offset += 2
+
+	for offset+4 <= end {
+		extType := binary.BigEndian.Uint16(body[offset : offset+2])
+		extLen := int(binary.BigEndian.Uint16(body[offset+2 : offset+4]))
+		if offset+4+extLen > end {
+			return
+		}
+		if extType == extSupportedVersions && extLen == 2 {
+			v := binary.BigEndian.Uint16(body[offset+4 : offset+6])
+			binary.BigEndian.PutUint16(body[offset+4:offset+6], TLSVersion(v))
+		}
+		offset += 4 + extLen
+	}
+}
@@ -0,0 +1,208 @@
+package dtls
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// Datagrams in the layout of OpenSSL's s_server -dtls
+const (
+	// HelloVerifyRequest in a DTLS 1.0 record, carrying a 20 byte cookie
+	helloVerifyRequestHex = "16 feff 0000 000000000000 0023" +
+		"03 000017 0000 000000 000017" +
+		"feff 14 5c1ab5d2e0f3a7b6c9d84e213f7a6b0c1d2e3f40"
+
+	// DTLS 1.2 ServerHello with renegotiation_info and extended_master_secret, followed by a ServerHelloDone
+	serverHello12Hex = "16 fefd 0000 000000000001 003d" +
+		"02 000031 0001 000000 000031" +
+		"fefd a1b2c3d4e5f60718293a4b5c6d7e8f900112233445566778899aabbccddeeff0 00 c02b 00" +
+		"0009 ff01000100 00170000" +
+		"16 fefd 0000 000000000002 000c" +
+		"0e 000000 0002 000000 000000"
+
+	// DTLS 1.3 ServerHello, selecting its version through supported_versions
+	serverHello13Hex = "16 fefd 0000 000000000000 003a" +
+		"02 00002e 0000 000000 00002e" +
+		"fefd a1b2c3d4e5f60718293a4b5c6d7e8f900112233445566778899aabbccddeeff0 00 1301 00" +
+		"0006 002b0002fefc"
+
+	// handshake_failure alert
+	alertHex = "15 fefd 0000 000000000000 0002 0228"
+
+	// DTLS 1.2 ClientHello without a cookie, as built by probes.BuildProbe
+	clientHelloHex = "16 fefd 0000 000000000000 0036" +
+		"01 00002a 0000 000000 00002a" +
+		"fefd 00000000000000000000000000000000000000000000000000000000000000ff 00 00 0002c02b 0100"
+)
+
+// unhex decodes a hex fixture, ignoring spaces
+func unhex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(strings.ReplaceAll(s, " ", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestTLSVersion(t *testing.T) {
+	tests := []struct {
+		version, want uint16
+	}{
+		{VersionDTLS10, 0x0302},
+		{VersionDTLS12, 0x0303},
+		{VersionDTLS13, 0x0304},
+		{0x0303, 0x0303},
+		{0xfefe, 0xfefe},
+	}
+	for _, tt := range tests {
+		if got := TLSVersion(tt.version); got != tt.want {
+			t.Errorf("TLSVersion(%04x) = %04x, want %04x", tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestParseRecords(t *testing.T) {
+	data := unhex(t, serverHello12Hex)
+	records := ParseRecords(data)
+	if len(records) != 2 {
+		t.Fatalf("%d records, want 2", len(records))
+	}
+	r := records[1]
+	if r.Type != recordTypeHandshake || r.Version != VersionDTLS12 || r.Epoch != 0 || r.Sequence != 2 || len(r.Fragment) != 12 {
+		t.Errorf("second record = %+v", r)
+	}
+	if !bytes.Equal(EncodeRecord(records[0]), data[:13+0x3d]) {
+		t.Errorf("EncodeRecord does not round trip")
+	}
+
+	// A truncated trailing record is ignored
+	if records := ParseRecords(data[:len(data)-1]); len(records) != 1 {
+		t.Errorf("%d records in a truncated datagram, want 1", len(records))
+	}
+	if records := ParseRecords(data[:12]); len(records) != 0 {
+		t.Errorf("%d records in a truncated header, want 0", len(records))
+	}
+}
+
+func TestHelloVerifyRequest(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		cookie string
+		ok     bool
+	}{
+		{"hello verify request", helloVerifyRequestHex, "5c1ab5d2e0f3a7b6c9d84e213f7a6b0c1d2e3f40", true},
+		{"server hello", serverHello12Hex, "", false},
+		{"alert", alertHex, "", false},
+		{"truncated", helloVerifyRequestHex[:len(helloVerifyRequestHex)-2], "", false},
+		{"empty", "", "", false},
+	}
+	for _, tt := range tests {
+		cookie, ok := HelloVerifyRequest(unhex(t, tt.data))
+		if ok != tt.ok || hex.EncodeToString(cookie) != tt.cookie {
+			t.Errorf("%s: HelloVerifyRequest = %x, %v", tt.name, cookie, ok)
+		}
+	}
+}
+
+func TestServerHelloBody(t *testing.T) {
+	whole := unhex(t, serverHello12Hex)
+	body, ok := ServerHelloBody(whole)
+	if !ok || len(body) != 0x31 || !bytes.Equal(body, whole[25:25+0x31]) {
+		t.Fatalf("ServerHelloBody = %x, %v", body, ok)
+	}
+
+	fragment := func(seq uint64, offset, length int) []byte {
+		h := EncodeHandshake(typeServerHello, 1, body)[:handshakeHeaderLength]
+		h[6], h[7], h[8] = byte(offset>>16), byte(offset>>8), byte(offset)
+		h[9], h[10], h[11] = byte(length>>16), byte(length>>8), byte(length)
+		return EncodeRecord(Record{Type: recordTypeHandshake, Version: VersionDTLS12, Sequence: seq, Fragment: append(h, body[offset:offset+length]...)})
+	}
+	first, second := fragment(1, 0, 20), fragment(2, 20, len(body)-20)
+
+	tests := []struct {
+		name string
+		data []byte
+		ok   bool
+	}{
+		{"fragmented", append(append([]byte{}, first...), second...), true},
+		{"out of order", append(append([]byte{}, second...), first...), false},
+		{"missing fragment", first, false},
+		{"encrypted epoch", append([]byte{0x16, 0xfe, 0xfd, 0x00, 0x01}, whole[5:]...), false},
+	}
+	for _, tt := range tests {
+		got, ok := ServerHelloBody(tt.data)
+		if ok != tt.ok || (ok && !bytes.Equal(got, body)) {
+			t.Errorf("%s: ServerHelloBody = %x, %v", tt.name, got, ok)
+		}
+	}
+}
+
+func TestToTLS(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{
+			"dtls 1.2 server hello", serverHello12Hex,
+			"16 0303 0035 02 000031" +
+				"0303 a1b2c3d4e5f60718293a4b5c6d7e8f900112233445566778899aabbccddeeff0 00 c02b 00" +
+				"0009 ff01000100 00170000",
+		},
+		{
+			"dtls 1.3 server hello", serverHello13Hex,
+			"16 0303 0032 02 00002e" +
+				"0303 a1b2c3d4e5f60718293a4b5c6d7e8f900112233445566778899aabbccddeeff0 00 1301 00" +
+				"0006 002b00020304",
+		},
+		{"alert", alertHex, "15 0303 0002 0228"},
+		{"hello verify request", helloVerifyRequestHex, ""},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		data := unhex(t, tt.data)
+		original := append([]byte{}, data...)
+		if got := ToTLS(data); !bytes.Equal(got, unhex(t, tt.want)) {
+			t.Errorf("%s: ToTLS = %x", tt.name, got)
+		}
+		if !bytes.Equal(data, original) {
+			t.Errorf("%s: ToTLS modified the datagram", tt.name)
+		}
+	}
+}
+
+func TestWithCookie(t *testing.T) {
+	hello := unhex(t, clientHelloHex)
+	cookie := unhex(t, "5c1ab5d2e0f3a7b6c9d84e213f7a6b0c1d2e3f40")
+
+	want := unhex(t, "16 fefd 0000 000000000001 004a"+
+		"01 00003e 0001 000000 00003e"+
+		"fefd 00000000000000000000000000000000000000000000000000000000000000ff 00"+
+		"14 5c1ab5d2e0f3a7b6c9d84e213f7a6b0c1d2e3f40 0002c02b 0100")
+	got, err := WithCookie(hello, cookie)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("WithCookie = %x", got)
+	}
+
+	// A second cookie replaces the first one
+	again, err := WithCookie(got, cookie[:4])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := ParseRecords(again); len(r) != 1 || r[0].Sequence != 2 || len(r[0].Fragment) != len(hello)-13+4 {
+		t.Errorf("WithCookie on a cookie hello = %x", again)
+	}
+
+	for _, data := range []string{serverHello12Hex, alertHex, clientHelloHex[:60], ""} {
+		if _, err := WithCookie(unhex(t, data), cookie); err == nil {
+			t.Errorf("WithCookie(%s) accepted", data)
+		}
+	}
+}
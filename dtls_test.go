@@ -0,0 +1,92 @@
+package gojarm
+
+import (
+	"bytes"
+	"encoding/hex"
+	"net"
+	"sync/atomic"
+	"testing"
+
+	"github.com/TheGejr/gojarm/dtls"
+	"github.com/TheGejr/gojarm/jarm"
+)
+
+// DTLS 1.2 HelloVerifyRequest and ServerHello replies of the fake server
+var (
+	dtlsCookie             = "5c1ab5d2e0f3a7b6c9d84e213f7a6b0c1d2e3f40"
+	dtlsHelloVerifyRequest = "16feff00000000000000000023" +
+		"030000170000000000000017" +
+		"feff14" + dtlsCookie
+	dtlsServerHello = "16fefd0000000000000001003d" +
+		"020000310001000000000031" +
+		"fefda1b2c3d4e5f60718293a4b5c6d7e8f900112233445566778899aabbccddeeff000c02b00" +
+		"0009ff0100010000170000"
+)
+
+// serveDTLS answers client hellos without a cookie with a HelloVerifyRequest, and client hellos with the cookie with a ServerHello
+func serveDTLS(t *testing.T, conn net.PacketConn, retries *int32) {
+	hvr, _ := hex.DecodeString(dtlsHelloVerifyRequest)
+	sh, _ := hex.DecodeString(dtlsServerHello)
+	cookie, _ := hex.DecodeString(dtlsCookie)
+
+	buff := make([]byte, 65535)
+	for {
+		n, addr, err := conn.ReadFrom(buff)
+		if err != nil {
+			return
+		}
+		records := dtls.ParseRecords(buff[:n])
+		if len(records) != 1 || len(records[0].Fragment) < 12+35 || records[0].Fragment[0] != 1 {
+			t.Errorf("unexpected datagram: %x", buff[:n])
+			continue
+		}
+
+		// version, random and session ID, followed by the cookie
+		hello := records[0].Fragment[12:]
+		offset := 35 + int(hello[34])
+		received := hello[offset+1 : offset+1+int(hello[offset])]
+
+		switch {
+		case len(received) == 0:
+			conn.WriteTo(hvr, addr)
+		case bytes.Equal(received, cookie) && records[0].Sequence == 1:
+			atomic.AddInt32(retries, 1)
+			conn.WriteTo(sh, addr)
+		default:
+			t.Errorf("unexpected cookie %x with sequence %d", received, records[0].Sequence)
+		}
+	}
+}
+
+func TestFingerprintDTLS(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var retries int32
+	done := make(chan int)
+	go func() {
+		serveDTLS(t, conn, &retries)
+		close(done)
+	}()
+
+	res := Fingerprint(Target{Host: "127.0.0.1", Port: conn.LocalAddr().(*net.UDPAddr).Port, Transport: TransportDTLS})
+	conn.Close()
+	<-done
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+
+	if n := int(atomic.LoadInt32(&retries)); n != len(res.Probes) || len(res.Probes) == 0 {
+		t.Errorf("%d client hellos with a cookie, want %d", n, len(res.Probes))
+	}
+	for _, pr := range res.Probes {
+		if pr.Raw != "c02b|0303||ff01-0017" {
+			t.Errorf("%s: raw = %q", pr.Probe.Name, pr.Raw)
+		}
+	}
+	if prefix, _ := jarm.SplitPrefix(res.Hash); prefix != jarm.DTLSPrefix || res.Hash == jarm.DTLSPrefix+ZeroHash {
+		t.Errorf("hash = %s", res.Hash)
+	}
+}
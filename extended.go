@@ -0,0 +1,76 @@
+package gojarm
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/TheGejr/gojarm/ciphers"
+	"github.com/TheGejr/gojarm/handshake"
+	"github.com/TheGejr/gojarm/jarm"
+)
+
+// ParseServerHelloExtended returns the extended features of a response, for the session ID that was sent
+//
+// The features are the selected key share group, the supported_versions value,
+// the compression method, whether the session ID was echoed and the alert received.
+func ParseServerHelloExtended(data []byte, sessionID []byte) string {
+	if level, desc, ok := handshake.ParseAlert(data); ok {
+		return fmt.Sprintf("||||%02x%02x", level, desc)
+	}
+
+	sh, err := handshake.ParseServerHello(data)
+	if err != nil {
+		return "||||"
+	}
+
+	group := ""
+	if ks, ok := sh.Extension(handshake.ExtKeyShare); ok && len(ks) >= 2 {
+		group = fmt.Sprintf("%04x", binary.BigEndian.Uint16(ks[:2]))
+	}
+
+	versions := ""
+	if sv, ok := sh.Extension(handshake.ExtSupportedVersions); ok {
+		versions = hex.EncodeToString(sv)
+	}
+
+	echo := "new"
+	if len(sh.SessionID) == 0 {
+		echo = "empty"
+	} else if bytes.Equal(sh.SessionID, sessionID) {
+		echo = "echo"
+	}
+
+	return fmt.Sprintf("%s|%s|%02x|%s|", group, versions, sh.Compression, echo)
+}
+
+// RawHashToExtendedHash converts an extended raw hash to an extended hash
+//
+// Every probe of the raw hash holds the standard raw fingerprint followed by
+// the extended features, separated by "|".
+func RawHashToExtendedHash(raw string) string {
+	fhash := ""
+	alpex := ""
+	empty := true
+	for _, probe := range strings.Split(raw, ",") {
+		comp := strings.Split(probe, "|")
+		if len(comp) != 9 {
+			return jarm.ExtendedPrefix + ZeroHash
+		}
+		if probe != "||||||||" {
+			empty = false
+		}
+		fhash = fhash + ciphers.ExtractCipherBytes(comp[0])
+		fhash = fhash + ciphers.ExtractVersionByte(comp[1])
+		alpex = alpex + strings.Join(comp[2:], "")
+	}
+	if empty {
+		return jarm.ExtendedPrefix + ZeroHash
+	}
+	hash256 := sha256.Sum256([]byte(alpex))
+	fhash += hex.EncodeToString(hash256[:])[0:32]
+	return jarm.ExtendedPrefix + fhash
+}
@@ -0,0 +1,74 @@
+package gojarm
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/TheGejr/gojarm/jarm"
+)
+
+func TestParseServerHelloExtended(t *testing.T) {
+	const random = "a1b2c3d4e5f60718293a4b5c6d7e8f900112233445566778899aabbccddeeff0"
+	tests := []struct {
+		name      string
+		data      string
+		sessionID string
+		want      string
+	}{
+		{
+			"tls 1.3",
+			"160303005a020000560303" + random + "00130100002e" +
+				"00330024001d00209d3c940d89690b84d08a60993c144eca684d1081287c834d5311bcf32bb9da1a002b00020304",
+			"", "001d|0304|00|empty|",
+		},
+		{
+			"echoed session id",
+			"16030300430200003f0303" + random + "080102030405060708c02f00000fff0100010000170000000b00020100",
+			"0102030405060708", "||00|echo|",
+		},
+		{
+			"new session id",
+			"16030300430200003f0303" + random + "080102030405060708c02f00000fff0100010000170000000b00020100",
+			"1112131415161718", "||00|new|",
+		},
+		{"alert", "15030300020228", "", "||||0228"},
+		{"not a server hello", "160303000401000000", "", "||||"},
+		{"no response", "", "", "||||"},
+	}
+
+	for _, tt := range tests {
+		data, _ := hex.DecodeString(tt.data)
+		sessionID, _ := hex.DecodeString(tt.sessionID)
+		if got := ParseServerHelloExtended(data, sessionID); got != tt.want {
+			t.Errorf("%s: ParseServerHelloExtended = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRawHashToExtendedHash(t *testing.T) {
+	tls12 := "c02f|0303||ff01-0017-000b" + "|" + "||00|echo|"
+	tls13 := "1301|0303||0033-002b" + "|" + "001d|0304|00|empty|"
+	alert := "|||" + "|" + "||||0228"
+	none := "|||" + "|" + "||||"
+
+	tests := []struct {
+		name string
+		raw  []string
+		want string
+	}{
+		{
+			"responses",
+			[]string{tls12, tls12, tls12, tls12, tls12, tls13, tls13, tls13, alert, none},
+			jarm.ExtendedPrefix + strings.Repeat("29d", 5) + strings.Repeat("41d", 3) + "000000" + "10af7b017ef371c5c13843c963a1b1df",
+		},
+		{"no responses", []string{none, none, none, none, none, none, none, none, none, none}, jarm.ExtendedPrefix + ZeroHash},
+		{"standard raw hash", []string{"c02f|0303||ff01", "|||"}, jarm.ExtendedPrefix + ZeroHash},
+	}
+
+	for _, tt := range tests {
+		if got := RawHashToExtendedHash(strings.Join(tt.raw, ",")); got != tt.want {
+			t.Errorf("%s: RawHashToExtendedHash = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/TheGejr/gojarm/ciphers"
 	"github.com/TheGejr/gojarm/models"
+	"github.com/TheGejr/gojarm/quic"
 	"github.com/TheGejr/gojarm/utils"
 )
 
@@ -23,18 +24,26 @@ func GetExtensions(details models.JarmOptions) []byte {
 	allExtensions = append(allExtensions, 0x00, 0x17, 0x00, 0x00)
 	allExtensions = append(allExtensions, 0x00, 0x01, 0x00, 0x01, 0x01)
 	allExtensions = append(allExtensions, 0xff, 0x01, 0x00, 0x01, 0x00)
-	allExtensions = append(allExtensions, 0x00, 0x0a, 0x00, 0x0a, 0x00, 0x08, 0x00, 0x1d, 0x00, 0x17, 0x00, 0x18, 0x00, 0x19)
+	allExtensions = append(allExtensions, ExtGetSupportedGroups(details)...)
 	allExtensions = append(allExtensions, 0x00, 0x0b, 0x00, 0x02, 0x01, 0x00)
 	allExtensions = append(allExtensions, 0x00, 0x23, 0x00, 0x00)
 	allExtensions = append(allExtensions, ExtGetALPN(details)...)
 	allExtensions = append(allExtensions, 0x00, 0x0d, 0x00, 0x14, 0x00, 0x12, 0x04, 0x03, 0x08, 0x04, 0x04, 0x01, 0x05, 0x03, 0x08, 0x05, 0x05, 0x01, 0x08, 0x06, 0x06, 0x01, 0x02, 0x01)
-	allExtensions = append(allExtensions, ExtGetKeyShare(grease)...)
+	if details.KeyShare != "" {
+		allExtensions = append(allExtensions, ExtGetHybridKeyShare(details, grease)...)
+	} else {
+		allExtensions = append(allExtensions, ExtGetKeyShare(grease)...)
+	}
 	allExtensions = append(allExtensions, 0x00, 0x2d, 0x00, 0x02, 0x01, 0x01)
 
 	if details.Version == tls.VersionTLS13 || details.V13Mode == "1.2_SUPPORT" {
 		allExtensions = append(allExtensions, ExtGetSupportedVersions(details, grease)...)
 	}
 
+	if details.Transport == models.TransportQUIC {
+		allExtensions = append(allExtensions, ExtGetQUICTransportParameters()...)
+	}
+
 	extensions := utils.GetUint16Bytes(len(allExtensions))
 	extensions = append(extensions, allExtensions...)
 	return extensions
@@ -81,6 +90,9 @@ func ExtGetALPN(details models.JarmOptions) []byte {
 			{0x02, 0x68, 0x71},
 		}
 	}
+	if details.Transport == models.TransportQUIC {
+		alpns = append(alpns, []byte{0x02, 0x68, 0x33})
+	}
 	if details.ExtensionOrder != "FORWARD" {
 		alpns = ciphers.MungCiphers(alpns, details.ExtensionOrder)
 	}
@@ -116,10 +128,36 @@ func ExtGetKeyShare(grease bool) []byte {
 	return ext
 }
 
+// ExtGetSupportedGroups returns an encoded supported groups extension
+//
+// Probes offering hybrid post-quantum key shares list those groups first.
+func ExtGetSupportedGroups(details models.JarmOptions) []byte {
+	groups := []byte{}
+	for _, group := range hybridGroups(details.KeyShare) {
+		groups = append(groups, utils.GetUint16Bytes(int(group))...)
+	}
+	groups = append(groups, 0x00, 0x1d, 0x00, 0x17, 0x00, 0x18, 0x00, 0x19)
+
+	ext := []byte{0x00, 0x0a}
+	ext = append(ext, utils.GetUint16Bytes(len(groups)+2)...)
+	ext = append(ext, utils.GetUint16Bytes(len(groups))...)
+	ext = append(ext, groups...)
+	return ext
+}
+
 // ExtGetSupportedVersions returns an encoded SupportedVersions extension
 func ExtGetSupportedVersions(details models.JarmOptions, grease bool) []byte {
 	tlsVersions := [][]byte{}
-	if details.V13Mode == "1.2_SUPPORT" {
+	if details.Transport == models.TransportDTLS {
+		tlsVersions = append(tlsVersions, []byte{0xfe, 0xff})
+		tlsVersions = append(tlsVersions, []byte{0xfe, 0xfd})
+		if details.V13Mode != "1.2_SUPPORT" {
+			tlsVersions = append(tlsVersions, []byte{0xfe, 0xfc})
+		}
+	} else if details.Transport == models.TransportQUIC && details.V13Mode != "1.2_SUPPORT" {
+		// QUIC clients must not offer versions older than TLS 1.3, RFC 9001
+		tlsVersions = append(tlsVersions, []byte{0x03, 0x04})
+	} else if details.V13Mode == "1.2_SUPPORT" {
 		tlsVersions = append(tlsVersions, []byte{0x03, 0x01})
 		tlsVersions = append(tlsVersions, []byte{0x03, 0x02})
 		tlsVersions = append(tlsVersions, []byte{0x03, 0x03})
@@ -147,3 +185,33 @@ func ExtGetSupportedVersions(details models.JarmOptions, grease bool) []byte {
 	ext = append(ext, ver...)
 	return ext
 }
+
+// ExtGetQUICTransportParameters returns an encoded QUIC transport parameters extension
+//
+// The parameters are those of a typical HTTP/3 client with an empty source connection ID.
+func ExtGetQUICTransportParameters() []byte {
+	params := []byte{}
+	for _, p := range []struct {
+		id    uint64
+		value []byte
+	}{
+		{0x01, quic.AppendVarint(nil, 30000)}, // max_idle_timeout
+		{0x03, quic.AppendVarint(nil, 1472)},  // max_udp_payload_size
+		{0x04, quic.AppendVarint(nil, 1<<20)}, // initial_max_data
+		{0x05, quic.AppendVarint(nil, 1<<18)}, // initial_max_stream_data_bidi_local
+		{0x06, quic.AppendVarint(nil, 1<<18)}, // initial_max_stream_data_bidi_remote
+		{0x07, quic.AppendVarint(nil, 1<<18)}, // initial_max_stream_data_uni
+		{0x08, quic.AppendVarint(nil, 100)},   // initial_max_streams_bidi
+		{0x09, quic.AppendVarint(nil, 100)},   // initial_max_streams_uni
+		{0x0f, []byte{}},                      // initial_source_connection_id
+	} {
+		params = quic.AppendVarint(params, p.id)
+		params = quic.AppendVarint(params, uint64(len(p.value)))
+		params = append(params, p.value...)
+	}
+
+	ext := []byte{0x00, 0x39}
+	ext = append(ext, utils.GetUint16Bytes(len(params))...)
+	ext = append(ext, params...)
+	return ext
+}
@@ -9,7 +9,8 @@ import (
 
 // ExtractExtensionInfo returns parsed extension information from a server hello response
 func ExtractExtensionInfo(data []byte, offset int, serverHelloLength int) string {
-	if len(data) < 85 || len(data) < (offset+53) {
+	// The extensions length has to be present, short server hellos may end right after it
+	if len(data) < offset+49 {
 		return "|"
 	}
 
@@ -21,8 +22,8 @@ func ExtractExtensionInfo(data []byte, offset int, serverHelloLength int) string
 		return "|"
 	}
 
-	if bytes.Equal(data[offset+50:offset+53], []byte{0x0e, 0xac, 0x0b}) ||
-		bytes.Equal(data[82:85], []byte{0x0f, 0xf0, 0x0b}) {
+	if (len(data) >= offset+53 && bytes.Equal(data[offset+50:offset+53], []byte{0x0e, 0xac, 0x0b})) ||
+		(len(data) >= 85 && bytes.Equal(data[82:85], []byte{0x0f, 0xf0, 0x0b})) {
 		return "|"
 	}
 
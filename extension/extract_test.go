@@ -0,0 +1,57 @@
+package extension
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestExtractExtensionInfo(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{
+			// 64 bytes, shorter than the buffer the reference implementation reads into
+			"short server hello",
+			"16 0303 003b 02 000037 0303 a1b2c3d4e5f60718293a4b5c6d7e8f900112233445566778899aabbccddeeff0 00 c02f 00" +
+				"000f ff01000100 00170000 000b00020100",
+			"|ff01-0017-000b",
+		},
+		{
+			"alpn",
+			"16 0303 003a 02 000036 0303 a1b2c3d4e5f60718293a4b5c6d7e8f900112233445566778899aabbccddeeff0 00 c02f 00" +
+				"000e ff01000100 0010 0005 0003 026832",
+			"h2|ff01-0010",
+		},
+		{
+			"session id",
+			"16 0303 0043 02 00003f 0303 a1b2c3d4e5f60718293a4b5c6d7e8f900112233445566778899aabbccddeeff0" +
+				"08 0102030405060708 c02f 00 000f ff01000100 00170000 000b00020100",
+			"|ff01-0017-000b",
+		},
+		{
+			"no extensions",
+			"16 0303 002a 02 000026 0303 a1b2c3d4e5f60718293a4b5c6d7e8f900112233445566778899aabbccddeeff0 00 c02f 00",
+			"|",
+		},
+		{
+			"truncated extension",
+			"16 0303 003b 02 000037 0303 a1b2c3d4e5f60718293a4b5c6d7e8f900112233445566778899aabbccddeeff0 00 c02f 00" +
+				"000f ff01000100 00170000 000b0002",
+			"|ff01-0017-000b",
+		},
+	}
+
+	for _, tt := range tests {
+		data, err := hex.DecodeString(strings.ReplaceAll(tt.data, " ", ""))
+		if err != nil {
+			t.Fatal(err)
+		}
+		length := int(data[3])<<8 | int(data[4])
+		if got := ExtractExtensionInfo(data, int(data[43]), length); got != tt.want {
+			t.Errorf("%s: ExtractExtensionInfo = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
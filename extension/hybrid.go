@@ -0,0 +1,97 @@
+package extension
+
+import (
+	"crypto/rand"
+
+	"github.com/TheGejr/gojarm/models"
+	"github.com/TheGejr/gojarm/utils"
+)
+
+// Hybrid post-quantum key exchange groups
+const (
+	GroupX25519MLKEM768        = 0x11ec
+	GroupX25519Kyber768Draft00 = 0x6399
+)
+
+// ML-KEM-768 parameters, FIPS 203
+const (
+	mlkemQ                    = 3329
+	mlkemN                    = 256
+	mlkemK                    = 3
+	mlkemEncapsulationKeySize = mlkemK*mlkemN*12/8 + 32
+)
+
+// hybridGroups returns the hybrid groups offered for a key share mode
+func hybridGroups(mode string) []uint16 {
+	switch mode {
+	case "X25519MLKEM768":
+		return []uint16{GroupX25519MLKEM768}
+	case "X25519KYBER768":
+		return []uint16{GroupX25519Kyber768Draft00}
+	case "HYBRID":
+		return []uint16{GroupX25519MLKEM768, GroupX25519Kyber768Draft00}
+	}
+	return nil
+}
+
+// ExtGetHybridKeyShare returns an encoded KeyShare extension with hybrid post-quantum shares
+//
+// Every hybrid group of the probe gets a correctly sized share, followed by
+// an X25519 share. X25519MLKEM768 shares hold the ML-KEM-768 key before the
+// X25519 key, X25519Kyber768Draft00 shares the X25519 key first.
+func ExtGetHybridKeyShare(details models.JarmOptions, grease bool) []byte {
+	shareExt := []byte{}
+	if grease {
+		shareExt = utils.RandomGrease()
+		shareExt = append(shareExt, 0x00, 0x01, 0x00)
+	}
+
+	for _, group := range hybridGroups(details.KeyShare) {
+		share := []byte{}
+		switch group {
+		case GroupX25519MLKEM768:
+			share = append(randomMLKEM768Key(), utils.RandomBytes(32)...)
+		case GroupX25519Kyber768Draft00:
+			share = append(utils.RandomBytes(32), randomMLKEM768Key()...)
+		}
+		shareExt = append(shareExt, utils.GetUint16Bytes(int(group))...)
+		shareExt = append(shareExt, utils.GetUint16Bytes(len(share))...)
+		shareExt = append(shareExt, share...)
+	}
+
+	shareExt = append(shareExt, 0x00, 0x1d)
+	shareExt = append(shareExt, 0x00, 0x20)
+	shareExt = append(shareExt, utils.RandomBytes(32)...)
+
+	ext := []byte{0x00, 0x33}
+	ext = append(ext, utils.GetUint16Bytes(len(shareExt)+2)...)
+	ext = append(ext, utils.GetUint16Bytes(len(shareExt))...)
+	ext = append(ext, shareExt...)
+	return ext
+}
+
+// randomMLKEM768Key returns a random, well-formed ML-KEM-768 encapsulation key
+//
+// Servers reject keys holding coefficients that are not reduced modulo q, so
+// the coefficients are sampled below q and packed in 12 bits each, followed
+// by the 32 byte seed.
+func randomMLKEM768Key() []byte {
+	key := make([]byte, 0, mlkemEncapsulationKeySize)
+	buf := make([]byte, 3)
+	coefficients := make([]uint16, 0, 2)
+
+	for len(key) < mlkemEncapsulationKeySize-32 {
+		rand.Read(buf)
+		for _, c := range []uint16{uint16(buf[0]) | uint16(buf[1]&0x0f)<<8, uint16(buf[1]>>4) | uint16(buf[2])<<4} {
+			if c < mlkemQ {
+				coefficients = append(coefficients, c)
+			}
+		}
+		for len(coefficients) >= 2 {
+			a, b := coefficients[0], coefficients[1]
+			key = append(key, byte(a), byte(a>>8)|byte(b<<4), byte(b>>4))
+			coefficients = coefficients[2:]
+		}
+	}
+	return append(key, utils.RandomBytes(32)...)
+}
@@ -0,0 +1,134 @@
+package extension
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+
+	"github.com/TheGejr/gojarm/models"
+)
+
+// keyShareEntry is a single entry of a client key_share extension
+type keyShareEntry struct {
+	group uint16
+	share []byte
+}
+
+// parseKeyShare checks the length fields of a client key_share extension and returns its entries
+func parseKeyShare(t *testing.T, ext []byte) []keyShareEntry {
+	t.Helper()
+	if len(ext) < 6 || binary.BigEndian.Uint16(ext[0:2]) != 0x0033 {
+		t.Fatalf("not a key_share extension: %x", ext[:6])
+	}
+	if outer := int(binary.BigEndian.Uint16(ext[2:4])); outer != len(ext)-4 {
+		t.Fatalf("extension length %d, want %d", outer, len(ext)-4)
+	}
+	if inner := int(binary.BigEndian.Uint16(ext[4:6])); inner != len(ext)-6 {
+		t.Fatalf("client shares length %d, want %d", inner, len(ext)-6)
+	}
+
+	entries := []keyShareEntry{}
+	for data := ext[6:]; len(data) > 0; {
+		if len(data) < 4 {
+			t.Fatalf("truncated key share entry: %x", data)
+		}
+		length := int(binary.BigEndian.Uint16(data[2:4]))
+		if len(data) < 4+length {
+			t.Fatalf("key share of %d bytes in %d remaining bytes", length, len(data)-4)
+		}
+		entries = append(entries, keyShareEntry{binary.BigEndian.Uint16(data[0:2]), data[4 : 4+length]})
+		data = data[4+length:]
+	}
+	return entries
+}
+
+// checkMLKEM768Key fails the test unless an encapsulation key only holds coefficients reduced modulo q
+func checkMLKEM768Key(t *testing.T, key []byte) {
+	t.Helper()
+	if len(key) != 1184 {
+		t.Fatalf("ML-KEM-768 encapsulation key is %d bytes, want 1184", len(key))
+	}
+	for i := 0; i+3 <= 1152; i += 3 {
+		a := uint16(key[i]) | uint16(key[i+1]&0x0f)<<8
+		b := uint16(key[i+1]>>4) | uint16(key[i+2])<<4
+		if a >= mlkemQ || b >= mlkemQ {
+			t.Fatalf("unreduced coefficient at byte %d: %d, %d", i, a, b)
+		}
+	}
+}
+
+func TestExtGetHybridKeyShare(t *testing.T) {
+	// X25519 hybrids pair the 1184 byte ML-KEM-768 key with a 32 byte X25519 key. The
+	// 1184+65 byte shares of SecP256r1MLKEM768 are not offered by any probe.
+	tests := []struct {
+		mode   string
+		groups []uint16
+	}{
+		{"X25519MLKEM768", []uint16{GroupX25519MLKEM768}},
+		{"X25519KYBER768", []uint16{GroupX25519Kyber768Draft00}},
+		{"HYBRID", []uint16{GroupX25519MLKEM768, GroupX25519Kyber768Draft00}},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		for _, grease := range []bool{false, true} {
+			entries := parseKeyShare(t, ExtGetHybridKeyShare(models.JarmOptions{KeyShare: tt.mode}, grease))
+			if grease {
+				if len(entries) == 0 || entries[0].group&0x0f0f != 0x0a0a || len(entries[0].share) != 1 {
+					t.Fatalf("%s: no GREASE share first", tt.mode)
+				}
+				entries = entries[1:]
+			}
+
+			if len(entries) != len(tt.groups)+1 {
+				t.Fatalf("%s: %d key shares, want %d", tt.mode, len(entries), len(tt.groups)+1)
+			}
+			for i, group := range tt.groups {
+				e := entries[i]
+				if e.group != group || len(e.share) != 1184+32 {
+					t.Errorf("%s: share %d is %04x with %d bytes", tt.mode, i, e.group, len(e.share))
+					continue
+				}
+				if group == GroupX25519MLKEM768 {
+					checkMLKEM768Key(t, e.share[:1184])
+				} else {
+					checkMLKEM768Key(t, e.share[32:])
+				}
+			}
+			if last := entries[len(entries)-1]; last.group != 0x001d || len(last.share) != 32 {
+				t.Errorf("%s: last share is %04x with %d bytes, want x25519", tt.mode, last.group, len(last.share))
+			}
+		}
+	}
+}
+
+func TestExtGetKeyShare(t *testing.T) {
+	for _, grease := range []bool{false, true} {
+		entries := parseKeyShare(t, ExtGetKeyShare(grease))
+		if want := map[bool]int{false: 1, true: 2}[grease]; len(entries) != want {
+			t.Fatalf("grease %v: %d key shares, want %d", grease, len(entries), want)
+		}
+		if last := entries[len(entries)-1]; last.group != 0x001d || len(last.share) != 32 {
+			t.Errorf("grease %v: share is %04x with %d bytes", grease, last.group, len(last.share))
+		}
+	}
+}
+
+func TestExtGetSupportedGroups(t *testing.T) {
+	tests := []struct {
+		mode string
+		want string
+	}{
+		{"", "000a000a0008001d001700180019"},
+		{"X25519MLKEM768", "000a000c000a11ec001d001700180019"},
+		{"X25519KYBER768", "000a000c000a6399001d001700180019"},
+		{"HYBRID", "000a000e000c11ec6399001d001700180019"},
+	}
+	for _, tt := range tests {
+		want, _ := hex.DecodeString(tt.want)
+		if got := ExtGetSupportedGroups(models.JarmOptions{KeyShare: tt.mode}); !bytes.Equal(got, want) {
+			t.Errorf("%q: ExtGetSupportedGroups = %x, want %x", tt.mode, got, want)
+		}
+	}
+}
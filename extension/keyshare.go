@@ -0,0 +1,38 @@
+package extension
+
+import (
+	"crypto/elliptic"
+	"crypto/rand"
+
+	"github.com/TheGejr/gojarm/utils"
+)
+
+// KeyShareForGroup returns a fresh key share for a group, as requested by a HelloRetryRequest
+//
+// Only the shares of groups offered in supported_groups can be generated.
+func KeyShareForGroup(group uint16) ([]byte, bool) {
+	var curve elliptic.Curve
+	switch group {
+	case 0x001d:
+		return utils.RandomBytes(32), true
+	case GroupX25519MLKEM768:
+		return append(randomMLKEM768Key(), utils.RandomBytes(32)...), true
+	case GroupX25519Kyber768Draft00:
+		return append(utils.RandomBytes(32), randomMLKEM768Key()...), true
+	case 0x0017:
+		curve = elliptic.P256()
+	case 0x0018:
+		curve = elliptic.P384()
+	case 0x0019:
+		curve = elliptic.P521()
+	default:
+		return nil, false
+	}
+
+	// NIST curve shares must be points on the curve, in uncompressed form
+	_, x, y, err := elliptic.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		return nil, false
+	}
+	return elliptic.Marshal(curve, x, y), true
+}
@@ -0,0 +1,39 @@
+package extension
+
+import (
+	"crypto/elliptic"
+	"testing"
+)
+
+func TestKeyShareForGroup(t *testing.T) {
+	tests := []struct {
+		group  uint16
+		length int
+		curve  elliptic.Curve
+	}{
+		{0x001d, 32, nil},
+		{0x0017, 65, elliptic.P256()},
+		{0x0018, 97, elliptic.P384()},
+		{0x0019, 133, elliptic.P521()},
+		{GroupX25519MLKEM768, 1216, nil},
+		{GroupX25519Kyber768Draft00, 1216, nil},
+	}
+	for _, tt := range tests {
+		share, ok := KeyShareForGroup(tt.group)
+		if !ok || len(share) != tt.length {
+			t.Errorf("KeyShareForGroup(%04x) = %d bytes, %v, want %d", tt.group, len(share), ok, tt.length)
+			continue
+		}
+		if tt.curve != nil {
+			if x, _ := elliptic.Unmarshal(tt.curve, share); x == nil {
+				t.Errorf("KeyShareForGroup(%04x) is not a point on the curve", tt.group)
+			}
+		}
+	}
+
+	for _, group := range []uint16{0x001e, 0x0100, 0x0000} {
+		if _, ok := KeyShareForGroup(group); ok {
+			t.Errorf("KeyShareForGroup(%04x) returned a share for an unsupported group", group)
+		}
+	}
+}
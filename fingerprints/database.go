@@ -0,0 +1,240 @@
+package fingerprints
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/TheGejr/gojarm/jarm"
+)
+
+// Entry is a labeled JARM hash
+type Entry struct {
+	Hash       string   `json:"hash"`
+	Label      string   `json:"label"`
+	Source     string   `json:"source,omitempty"`
+	Confidence float64  `json:"confidence,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+}
+
+// Match is an entry found by a nearest-neighbour lookup
+type Match struct {
+	Entry
+	Similarity jarm.Similarity
+}
+
+// Database holds labeled JARM hashes and supports lookups on them
+//
+// Prefixed hashes of the standard length, such as DTLS and QUIC hashes, can be stored as
+// well and only match hashes with the same prefix, see jarm.SplitPrefix.
+type Database struct {
+	mu       sync.RWMutex
+	entries  []Entry
+	byHash   map[hashKey][]int
+	byPrefix map[string][]int
+}
+
+// hashKey identifies a hash in the indexes
+type hashKey struct {
+	prefix string
+	hash   jarm.Hash
+}
+
+// parseKey validates a hash, with its prefix, and returns its key
+func parseKey(hash string) (hashKey, error) {
+	prefix, bare := jarm.SplitPrefix(strings.TrimSpace(hash))
+	h, err := jarm.ParseHash(bare)
+	return hashKey{prefix: prefix, hash: h}, err
+}
+
+// cipherPrefix returns the prefix index key of the hash
+func (k hashKey) cipherPrefix() string {
+	return k.prefix + k.hash.CipherPrefix()
+}
+
+// New returns an empty database
+func New() *Database {
+	return &Database{
+		byHash:   map[hashKey][]int{},
+		byPrefix: map[string][]int{},
+	}
+}
+
+// Add adds an entry to the database, replacing an existing entry with the same hash and label
+func (db *Database) Add(e Entry) error {
+	e, h, err := normalize(e)
+	if err != nil {
+		return err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.add(e, h)
+	return nil
+}
+
+// Replace adds entries to the database, removing all existing entries for their hashes first
+//
+// Entries sharing a hash within the replacement are all kept, so a hash can
+// still carry several labels. Nothing is changed if an entry is invalid.
+func (db *Database) Replace(entries []Entry) error {
+	entries = append([]Entry{}, entries...)
+	hashes := make([]hashKey, len(entries))
+	for i := range entries {
+		e, h, err := normalize(entries[i])
+		if err != nil {
+			return fmt.Errorf("entry %d: %s", i, err)
+		}
+		entries[i] = e
+		hashes[i] = h
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	replaced := map[hashKey]bool{}
+	for _, h := range hashes {
+		replaced[h] = true
+	}
+	db.removeHashes(replaced)
+
+	for i, e := range entries {
+		db.add(e, hashes[i])
+	}
+	return nil
+}
+
+// Remove removes all entries for a hash and returns the number of entries removed
+func (db *Database) Remove(hash string) int {
+	h, err := parseKey(hash)
+	if err != nil {
+		return 0
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.removeHashes(map[hashKey]bool{h: true})
+}
+
+// normalize validates an entry and returns it with its hash in canonical form
+func normalize(e Entry) (Entry, hashKey, error) {
+	e.Hash = strings.ToLower(strings.TrimSpace(e.Hash))
+	h, err := parseKey(e.Hash)
+	return e, h, err
+}
+
+func (db *Database) add(e Entry, h hashKey) {
+	for _, i := range db.byHash[h] {
+		if db.entries[i].Label == e.Label {
+			db.entries[i] = e
+			return
+		}
+	}
+
+	db.entries = append(db.entries, e)
+	i := len(db.entries) - 1
+	db.byHash[h] = append(db.byHash[h], i)
+	db.byPrefix[h.cipherPrefix()] = append(db.byPrefix[h.cipherPrefix()], i)
+}
+
+// removeHashes removes the entries of the hashes and rebuilds the indexes
+func (db *Database) removeHashes(hashes map[hashKey]bool) int {
+	removed := 0
+	for h := range hashes {
+		removed += len(db.byHash[h])
+	}
+	if removed == 0 {
+		return 0
+	}
+
+	entries := db.entries
+	db.entries = nil
+	db.byHash = map[hashKey][]int{}
+	db.byPrefix = map[string][]int{}
+	for _, e := range entries {
+		h, _ := parseKey(e.Hash)
+		if !hashes[h] {
+			db.add(e, h)
+		}
+	}
+	return removed
+}
+
+// Len returns the number of entries in the database
+func (db *Database) Len() int {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return len(db.entries)
+}
+
+// Entries returns a copy of all entries in the database
+func (db *Database) Entries() []Entry {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return append([]Entry{}, db.entries...)
+}
+
+// Lookup returns the entries matching the hash exactly
+func (db *Database) Lookup(hash string) []Entry {
+	h, err := parseKey(hash)
+	if err != nil {
+		return []Entry{}
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return db.collect(db.byHash[h])
+}
+
+// LookupPrefix returns the entries sharing the cipher and version prefix of the hash
+//
+// Both a full hash and a bare 30-character prefix are accepted.
+func (db *Database) LookupPrefix(hash string) []Entry {
+	prefix, bare := jarm.SplitPrefix(hash)
+	if len(bare) < jarm.PrefixLength {
+		return []Entry{}
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return db.collect(db.byPrefix[prefix+strings.ToLower(bare[:jarm.PrefixLength])])
+}
+
+// Nearest returns up to n entries ordered by their similarity to the hash
+//
+// Only entries with the same prefix as the hash are comparable to it.
+func (db *Database) Nearest(hash string, n int) ([]Match, error) {
+	if _, err := parseKey(hash); err != nil {
+		return nil, err
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	matches := []Match{}
+	for _, e := range db.entries {
+		sim, err := jarm.Compare(hash, e.Hash)
+		if err != nil {
+			continue
+		}
+		matches = append(matches, Match{Entry: e, Similarity: sim})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Similarity.Distance < matches[j].Similarity.Distance
+	})
+
+	if n > 0 && len(matches) > n {
+		matches = matches[:n]
+	}
+	return matches, nil
+}
+
+func (db *Database) collect(idx []int) []Entry {
+	entries := []Entry{}
+	for _, i := range idx {
+		entries = append(entries, db.entries[i])
+	}
+	return entries
+}
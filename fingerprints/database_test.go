@@ -0,0 +1,114 @@
+package fingerprints
+
+import (
+	"strings"
+	"testing"
+)
+
+const (
+	hashA = "07d14d16d21d21d07c42d41d00041d24a458a375eef0c576d23a7bab9a9fb1"
+	hashB = "29d29d00029d29d00041d43d00041d2aa5ce6a70de7ba95aef77a77b00a0af"
+)
+
+func labels(entries []Entry) string {
+	l := []string{}
+	for _, e := range entries {
+		l = append(l, e.Label)
+	}
+	return strings.Join(l, ",")
+}
+
+func TestLoadOverrides(t *testing.T) {
+	db := New()
+	if err := db.LoadCSV(strings.NewReader(hashA + ",Cobalt Strike\n" + hashA + ",Sliver\n" + hashB + ",nginx\n")); err != nil {
+		t.Fatal(err)
+	}
+	if got := labels(db.Lookup(hashA)); got != "Cobalt Strike,Sliver" {
+		t.Fatalf("Lookup after first load = %q", got)
+	}
+
+	// A later file replaces every label of the hashes it lists
+	if err := db.LoadJSON(strings.NewReader(`[{"hash": "` + strings.ToUpper(hashA) + `", "label": "Benign appliance"}]`)); err != nil {
+		t.Fatal(err)
+	}
+	if got := labels(db.Lookup(hashA)); got != "Benign appliance" {
+		t.Errorf("Lookup after override = %q", got)
+	}
+	if got := labels(db.Lookup(hashB)); got != "nginx" {
+		t.Errorf("Lookup of untouched hash = %q", got)
+	}
+	if got := labels(db.LookupPrefix(hashA)); got != "Benign appliance" {
+		t.Errorf("LookupPrefix after override = %q", got)
+	}
+
+	// Invalid documents leave the database untouched
+	if err := db.LoadCSV(strings.NewReader(hashB + ",Caddy\nnot-a-hash,broken\n")); err == nil {
+		t.Error("LoadCSV accepted an invalid hash")
+	}
+	if got := labels(db.Lookup(hashB)); got != "nginx" {
+		t.Errorf("Lookup after failed load = %q", got)
+	}
+
+	if n := db.Remove(hashB); n != 1 || db.Len() != 1 {
+		t.Errorf("Remove = %d, Len = %d", n, db.Len())
+	}
+}
+
+func TestLoadSignaturesOverridesEmbedded(t *testing.T) {
+	db := New()
+	if _, err := db.LoadSignatures(strings.NewReader(string(embeddedSignatures))); err != nil {
+		t.Fatal(err)
+	}
+	if len(db.Lookup(hashA)) == 0 {
+		t.Fatal("embedded set lost its entries")
+	}
+
+	doc := `{"schema": 1, "version": "local", "entries": [{"hash": "` + hashA + `", "label": "Corrected"}]}`
+	version, err := db.LoadSignatures(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != "local" {
+		t.Errorf("version = %q", version)
+	}
+	if got := labels(db.Lookup(hashA)); got != "Corrected" {
+		t.Errorf("Lookup = %q, want the user label only", got)
+	}
+}
+
+func TestPrefixedHashes(t *testing.T) {
+	db := New()
+	entries := []Entry{
+		{Hash: hashB, Label: "tls"},
+		{Hash: "jd1_" + hashB, Label: "dtls"},
+	}
+	if err := db.Replace(entries); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		lookup string
+		want   string
+	}{
+		{hashB, "tls"},
+		{"jd1_" + hashB, "dtls"},
+		{"JD1_" + strings.ToUpper(hashB), "dtls"},
+		{"jx1_" + hashB, ""},
+	}
+	for _, tt := range tests {
+		if got := labels(db.Lookup(tt.lookup)); got != tt.want {
+			t.Errorf("Lookup(%s) = %q, want %q", tt.lookup, got, tt.want)
+		}
+		if got := labels(db.LookupPrefix(tt.lookup)); got != tt.want {
+			t.Errorf("LookupPrefix(%s) = %q, want %q", tt.lookup, got, tt.want)
+		}
+	}
+
+	matches, err := db.Nearest("jd1_"+hashB, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 || matches[0].Label != "dtls" {
+		t.Errorf("Nearest = %+v", matches)
+	}
+}
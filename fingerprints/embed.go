@@ -0,0 +1,79 @@
+package fingerprints
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// SchemaVersion is the version of the signature document format understood by this package
+const SchemaVersion = 1
+
+// Signatures is the document format of a versioned signature set
+//
+// The embedded set uses this format, with entries following the JSON form of Entry:
+//
+//	{
+//	  "schema": 1,
+//	  "version": "2023.02.0",
+//	  "entries": [
+//	    {"hash": "...", "label": "...", "source": "...", "confidence": 0.5, "tags": ["..."]}
+//	  ]
+//	}
+type Signatures struct {
+	Schema  int     `json:"schema"`
+	Version string  `json:"version"`
+	Entries []Entry `json:"entries"`
+}
+
+//go:embed data/signatures.json
+var embeddedSignatures []byte
+
+var (
+	defaultOnce     sync.Once
+	defaultDatabase *Database
+	defaultVersion  string
+)
+
+// Default returns the shared database holding the embedded signature set
+//
+// Entries added to it at runtime, e.g. through LoadFile, are seen by every user of Default.
+func Default() *Database {
+	defaultOnce.Do(func() {
+		defaultDatabase = New()
+		version, err := defaultDatabase.LoadSignatures(bytes.NewReader(embeddedSignatures))
+		if err != nil {
+			panic("gojarm: invalid embedded signatures: " + err.Error())
+		}
+		defaultVersion = version
+	})
+	return defaultDatabase
+}
+
+// DefaultVersion returns the version of the embedded signature set
+func DefaultVersion() string {
+	Default()
+	return defaultVersion
+}
+
+// LoadSignatures adds the entries of a versioned signature document and returns its version
+//
+// The last loaded document wins: every hash it lists loses the entries
+// loaded before, so user signatures can correct the embedded labels.
+func (db *Database) LoadSignatures(r io.Reader) (string, error) {
+	sigs := Signatures{}
+	if err := json.NewDecoder(r).Decode(&sigs); err != nil {
+		return "", err
+	}
+	if sigs.Schema != SchemaVersion {
+		return "", fmt.Errorf("unsupported signature schema: %d", sigs.Schema)
+	}
+
+	if err := db.Replace(sigs.Entries); err != nil {
+		return "", err
+	}
+	return sigs.Version, nil
+}
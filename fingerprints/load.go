@@ -0,0 +1,107 @@
+package fingerprints
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// LoadCSV adds the entries of a CSV document to the database
+//
+// The columns are hash, label, source, confidence and tags, where only
+// hash and label are required and tags are separated by semicolons.
+// A header row starting with "hash" is skipped. Like LoadSignatures, the
+// entries replace those loaded before for the same hashes.
+func (db *Database) LoadCSV(r io.Reader) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+
+	entries := []Entry{}
+	line := 0
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return db.Replace(entries)
+		}
+		if err != nil {
+			return err
+		}
+		line++
+
+		if line == 1 && strings.EqualFold(record[0], "hash") {
+			continue
+		}
+		if len(record) < 2 {
+			return fmt.Errorf("line %d: expected at least hash and label", line)
+		}
+
+		e := Entry{
+			Hash:  record[0],
+			Label: record[1],
+		}
+		if len(record) > 2 {
+			e.Source = record[2]
+		}
+		if len(record) > 3 && record[3] != "" {
+			e.Confidence, err = strconv.ParseFloat(record[3], 64)
+			if err != nil {
+				return fmt.Errorf("line %d: invalid confidence: %s", line, record[3])
+			}
+		}
+		if len(record) > 4 && record[4] != "" {
+			e.Tags = strings.Split(record[4], ";")
+		}
+
+		if _, _, err := normalize(e); err != nil {
+			return fmt.Errorf("line %d: %s", line, err)
+		}
+		entries = append(entries, e)
+	}
+}
+
+// LoadJSON adds the entries of a JSON array, or a versioned signature document, to the database
+//
+// Like LoadSignatures, the entries replace those loaded before for the same hashes.
+func (db *Database) LoadJSON(r io.Reader) error {
+	raw := json.RawMessage{}
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return err
+	}
+
+	if trimmed := bytes.TrimSpace(raw); len(trimmed) > 0 && trimmed[0] == '{' {
+		_, err := db.LoadSignatures(bytes.NewReader(trimmed))
+		return err
+	}
+
+	entries := []Entry{}
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		return err
+	}
+
+	return db.Replace(entries)
+}
+
+// LoadFile adds the entries of a CSV or JSON file to the database, based on its extension
+func (db *Database) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return db.LoadCSV(f)
+	case ".json":
+		return db.LoadJSON(f)
+	}
+	return fmt.Errorf("unsupported fingerprint file: %s", path)
+}
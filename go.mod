@@ -3,3 +3,5 @@ module github.com/TheGejr/gojarm
 go 1.19
 
 require golang.org/x/net v0.6.0
+
+require golang.org/x/text v0.7.0 // indirect
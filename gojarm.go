@@ -14,8 +14,14 @@ import (
 
 	"github.com/TheGejr/gojarm/ciphers"
 	"github.com/TheGejr/gojarm/extension"
+	"github.com/TheGejr/gojarm/fingerprints"
+	"github.com/TheGejr/gojarm/handshake"
+	"github.com/TheGejr/gojarm/jarm"
 	"github.com/TheGejr/gojarm/models"
 	"github.com/TheGejr/gojarm/probes"
+	"github.com/TheGejr/gojarm/protocols"
+	"github.com/TheGejr/gojarm/scope"
+	"github.com/TheGejr/gojarm/starttls"
 	"github.com/TheGejr/gojarm/utils"
 )
 
@@ -29,7 +35,7 @@ import (
 //////
 
 // Empty JARM hash
-var ZeroHash = "00000000000000000000000000000000000000000000000000000000000000"
+const ZeroHash = "00000000000000000000000000000000000000000000000000000000000000"
 
 // Target struct
 type Target struct {
@@ -38,13 +44,88 @@ type Target struct {
 
 	Retries int
 	Backoff func(r, m int) time.Duration
+
+	// Database is used to label the resulting hash, if set
+	Database *fingerprints.Database
+
+	// Extended computes an extended hash alongside the standard JARM hash
+	Extended bool
+
+	// Scope restricts the hosts, addresses and ports that may be contacted, if set
+	Scope *scope.Policy
+
+	// PreCheck makes a single connection attempt before probing, and stops
+	// after the first probe if the target does not answer with TLS. DTLS and
+	// QUIC targets skip the connection attempt, as UDP has no connections.
+	PreCheck bool
+
+	// StartTLS upgrades every connection to TLS before the probe is sent, if set.
+	// It only applies to TLS over TCP, other transports are rejected.
+	StartTLS starttls.Negotiator
+
+	// Legacy sends the legacy SSLv2, SSLv3 and TLS 1.0 probes after the
+	// standard probes, computing Result.LegacyHash. It only applies to TLS over TCP.
+	Legacy bool
+
+	// PostQuantum sends probes offering hybrid post-quantum key shares after
+	// the standard probes, setting Result.PostQuantumProbes. It only applies to TLS over TCP.
+	PostQuantum bool
+
+	// FollowRetry answers HelloRetryRequests to TLS over TCP probes with a second
+	// client hello carrying the requested key share, setting ProbeResult.Retry
+	FollowRetry bool
+
+	// Transport is TransportDTLS or TransportQUIC to fingerprint over UDP, TLS over TCP is used by default
+	Transport string
 }
 
 // Result struct
 type Result struct {
 	Target Target
 	Hash   string
-	Error  error
+	Status Status
+	// Protocol is the protocol detected from the responses, such as "tls" or "ssh"
+	Protocol string
+	Labels   []fingerprints.Entry
+	Probes   []ProbeResult
+
+	// ExtendedHash is only set for targets with Extended enabled
+	ExtendedHash string
+
+	// LegacyHash and LegacyProbes are only set for targets with Legacy enabled
+	LegacyHash   string
+	LegacyProbes []ProbeResult
+
+	// PostQuantumProbes is only set for targets with PostQuantum enabled, see ProbeResult.Group
+	PostQuantumProbes []ProbeResult
+
+	Error error
+}
+
+// ProbeResult holds the outcome of a single probe
+type ProbeResult struct {
+	Probe models.JarmOptions
+	// Raw is the raw JARM component of the probe, empty if the probe could not be sent
+	Raw  string
+	JA3S string
+	JA4S string
+	// Extended holds the extended features of the response, see ParseServerHelloExtended
+	Extended string
+	// Response holds the bytes received for the probe
+	Response []byte
+	// Protocol is the protocol detected from the response, empty if nothing was received
+	Protocol string
+	// Group is the name of the key share group the server selected, empty if it sent none
+	// or answered with a HelloRetryRequest
+	Group string
+	// HelloRetryRequest is set if the server asked for another key share, naming the RequestedGroup.
+	// The raw JARM component still describes the HelloRetryRequest, as the reference implementation does.
+	HelloRetryRequest bool
+	RequestedGroup    string
+	// Retry holds the outcome of the second client hello, for targets with FollowRetry enabled
+	Retry *ProbeResult
+	// Error is set if the connection could not be upgraded to TLS
+	Error error
 }
 
 // ParseServerHello returns the raw fingerprint for a server hello response
@@ -105,67 +186,257 @@ func RawHashToFuzzyHash(raw string) string {
 	return fhash
 }
 
+// Identify returns the labels of the embedded signature set matching the result
+func Identify(r Result) []fingerprints.Entry {
+	return fingerprints.Default().Lookup(r.Hash)
+}
+
 func Fingerprint(t Target) (result Result) {
-	// TODO: Check if target is valid (ip and port)
+	if t.Host == "" {
+		return Result{
+			Error: errors.New("invalid target: empty host"),
+		}
+	}
 
-	results := []string{}
+	if !utils.ValidPort(t.Port) {
+		return Result{
+			Error: fmt.Errorf("invalid target port: %d", t.Port),
+		}
+	}
 
-	for _, probe := range probes.GetProbes(t.Host, t.Port) {
-		dialer := proxy.FromEnvironmentUsing(&net.Dialer{Timeout: time.Second * 2})
-		addr := net.JoinHostPort(t.Host, fmt.Sprintf("%d", t.Port))
+	if t.StartTLS != nil && t.Transport != "" {
+		return Result{
+			Error: fmt.Errorf("invalid target: starttls is not supported over %s", t.Transport),
+		}
+	}
 
-		conn := net.Conn(nil)
-		n := 0
+	if t.PreCheck {
+		status, err := preCheck(t)
+		if err != nil {
+			return Result{
+				Error: err,
+			}
+		}
+		if status != StatusOK {
+			return Result{
+				Target: t,
+				Hash:   transportPrefix(t.Transport) + ZeroHash,
+				Status: status,
+			}
+		}
+	}
+
+	results := []string{}
+	extended := []string{}
+	probeResults := []ProbeResult{}
 
-		for conn == nil && n <= t.Retries {
-			// Ignoring errors since error messages was already dropped
-			// conn == nil means an error occured
-			conn, _ = dialer.Dial("tcp", addr)
-			if conn != nil || t.Retries == 0 {
-				break
+	probeSet := probes.GetProbes(t.Host, t.Port)
+	switch t.Transport {
+	case TransportDTLS:
+		probeSet = probes.GetDTLSProbes(t.Host, t.Port)
+	case TransportQUIC:
+		probeSet = probes.GetQUICProbes(t.Host, t.Port)
+	}
+
+	for i, probe := range probeSet {
+		pr, err := probeTarget(t, probe)
+		if err != nil {
+			return Result{
+				Error: err,
 			}
+		}
 
-			backoff := t.Backoff
-			if backoff == nil {
-				backoff = utils.DefualtBackoff
+		// The remaining probes are pointless if the first one was answered by something else than TLS
+		if t.PreCheck && i == 0 {
+			status, protocol := responseStatus([]ProbeResult{pr})
+			switch status {
+			case StatusNonTLS, StatusUpgradeRefused, StatusUpgradeFailed, StatusNegotiationFailure:
+				return Result{
+					Target:   t,
+					Hash:     transportPrefix(t.Transport) + ZeroHash,
+					Status:   status,
+					Protocol: protocol,
+					Probes:   []ProbeResult{pr},
+				}
 			}
+		}
 
-			time.Sleep(backoff(n, t.Retries))
+		results = append(results, pr.Raw)
+		extended = append(extended, pr.Raw+"|"+pr.Extended)
+		probeResults = append(probeResults, pr)
+	}
 
-			n++
-		}
+	result = Result{
+		Target: t,
+		Hash:   RawHashToFuzzyHash(strings.Join(results, ",")),
+		Probes: probeResults,
+	}
+	result.Status, result.Protocol = responseStatus(probeResults)
+
+	if t.Extended {
+		result.ExtendedHash = RawHashToExtendedHash(strings.Join(extended, ","))
+	}
 
-		if conn == nil {
+	if t.Legacy && t.Transport == "" {
+		hash, legacyProbes, err := legacyFingerprint(t)
+		if err != nil {
 			return Result{
-				Error: errors.New("failed to establish a connection to the host"),
+				Error: err,
+			}
+		}
+		result.LegacyHash = hash
+		result.LegacyProbes = legacyProbes
+	}
+
+	if t.PostQuantum && t.Transport == "" {
+		for _, probe := range probes.GetPostQuantumProbes(t.Host, t.Port) {
+			pr, err := probeTarget(t, probe)
+			if err != nil {
+				return Result{
+					Error: err,
+				}
 			}
+			result.PostQuantumProbes = append(result.PostQuantumProbes, pr)
+		}
+	}
+
+	// DTLS and QUIC hashes are prefixed, so they only match hashes of the same transport
+	if prefix := transportPrefix(t.Transport); prefix != "" {
+		result.Hash = prefix + result.Hash
+		if result.ExtendedHash != "" {
+			result.ExtendedHash = prefix + result.ExtendedHash
+		}
+	}
+	if t.Database != nil {
+		result.Labels = t.Database.Lookup(result.Hash)
+	}
+
+	return result
+}
+
+// transportPrefix returns the hash prefix of a transport, which is empty for TLS over TCP
+func transportPrefix(transport string) string {
+	switch transport {
+	case TransportDTLS:
+		return jarm.DTLSPrefix
+	case TransportQUIC:
+		return jarm.QUICPrefix
+	}
+	return ""
+}
+
+// dialTarget establishes a connection to the target, retrying as configured
+func dialTarget(t Target) (net.Conn, error) {
+	network := "tcp"
+	dialer := proxy.FromEnvironmentUsing(&net.Dialer{Timeout: time.Second * 2})
+
+	// Proxies only carry TCP, so datagrams are always sent directly
+	if t.Transport == TransportDTLS || t.Transport == TransportQUIC {
+		network = "udp"
+		dialer = &net.Dialer{Timeout: time.Second * 2}
+	}
+	if t.Scope != nil {
+		dialer = t.Scope.Dialer(dialer)
+	}
+	addr := net.JoinHostPort(t.Host, fmt.Sprintf("%d", t.Port))
+
+	for n := 0; n <= t.Retries; n++ {
+		conn, err := dialer.Dial(network, addr)
+		if err == nil {
+			return conn, nil
+		}
+
+		// Scope violations will not go away by retrying
+		if errors.Is(err, scope.ErrOutOfScope) {
+			return nil, err
+		}
+
+		if n == t.Retries {
+			break
+		}
+
+		backoff := t.Backoff
+		if backoff == nil {
+			backoff = utils.DefualtBackoff
 		}
 
-		data := probes.BuildProbe(probe)
-		conn.SetWriteDeadline(time.Now().Add(time.Second * 5))
-		_, err := conn.Write(data)
+		time.Sleep(backoff(n, t.Retries))
+	}
+
+	return nil, errors.New("failed to establish a connection to the host")
+}
+
+// probeTarget sends a single probe to the target and parses the response
+//
+// An error is only returned when no connection could be established.
+func probeTarget(t Target, probe models.JarmOptions) (ProbeResult, error) {
+	pr := ProbeResult{Probe: probe}
+
+	switch t.Transport {
+	case TransportDTLS:
+		return probeDTLS(t, probe)
+	case TransportQUIC:
+		return probeQUIC(t, probe)
+	}
+
+	conn, err := dialTarget(t)
+	if err != nil {
+		return pr, err
+	}
+
+	if t.StartTLS != nil {
+		upgraded, err := t.StartTLS.Negotiate(conn, t.Host)
 		if err != nil {
-			results = append(results, "")
 			conn.Close()
-			continue
+			pr.Error = err
+			return pr, nil
 		}
+		conn = upgraded
+	}
 
-		conn.SetReadDeadline(time.Now().Add(time.Second * 5))
-		buff := make([]byte, 1484)
-		conn.Read(buff)
+	data := probes.BuildProbe(probe)
+	conn.SetWriteDeadline(time.Now().Add(time.Second * 5))
+	_, err = conn.Write(data)
+	if err != nil {
 		conn.Close()
+		return pr, nil
+	}
 
-		ans, err := ParseServerHello(buff, probe)
-		if err != nil {
-			results = append(results, "")
-			continue
-		}
+	conn.SetReadDeadline(time.Now().Add(time.Second * 5))
+	buff := make([]byte, 1484)
+	n, _ := conn.Read(buff)
+	pr.Response = buff[:n]
+	if n > 0 {
+		pr.Protocol = protocols.Identify(pr.Response)
+	}
 
-		results = append(results, ans)
+	sh := fingerprintResponse(&pr, t, pr.Response, handshake.ClientHelloSessionID(data), 't', nil)
+	if pr.HelloRetryRequest && t.FollowRetry {
+		pr.Retry = followRetry(t, conn, probe, data, sh)
 	}
+	conn.Close()
 
-	return Result{
-		Target: t,
-		Hash:   RawHashToFuzzyHash(strings.Join(results, ",")),
+	return pr, nil
+}
+
+// fingerprintResponse sets the raw, extended, JA3S and JA4S fingerprints of a probe result
+//
+// The response is in TLS record format. The JA3S and JA4S are computed on the
+// server hello parsed from it, unless sh is given, and the server hello is returned.
+func fingerprintResponse(pr *ProbeResult, t Target, response []byte, sessionID []byte, transport byte, sh *handshake.ServerHello) *handshake.ServerHello {
+	pr.Raw, _ = ParseServerHello(response, pr.Probe)
+
+	if t.Extended {
+		pr.Extended = ParseServerHelloExtended(response, sessionID)
+	}
+
+	if sh == nil {
+		sh, _ = handshake.ParseServerHello(response)
+	}
+	if sh != nil {
+		pr.JA3S = sh.JA3S()
+		pr.JA4S = sh.JA4S(transport)
+		setKeyShare(pr, sh)
 	}
+	return sh
 }
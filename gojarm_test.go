@@ -0,0 +1,59 @@
+package gojarm
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/TheGejr/gojarm/models"
+	"github.com/TheGejr/gojarm/starttls"
+)
+
+func TestFingerprintValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		target Target
+	}{
+		{"empty host", Target{Port: 443}},
+		{"invalid port", Target{Host: "127.0.0.1", Port: 70000}},
+		{"starttls over dtls", Target{Host: "127.0.0.1", Port: 443, Transport: TransportDTLS, StartTLS: starttls.SMTP{}}},
+		{"starttls over quic", Target{Host: "127.0.0.1", Port: 443, Transport: TransportQUIC, StartTLS: starttls.SMTP{}}},
+	}
+
+	for _, tt := range tests {
+		if res := Fingerprint(tt.target); res.Error == nil {
+			t.Errorf("%s: no validation error", tt.name)
+		}
+	}
+}
+
+func TestParseServerHello(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{
+			"short server hello",
+			"160303003b020000370303a1b2c3d4e5f60718293a4b5c6d7e8f900112233445566778899aabbccddeeff000c02f00" +
+				"000fff0100010000170000000b00020100",
+			"c02f|0303||ff01-0017-000b",
+		},
+		{"alert", "15030300020228", "|||"},
+		{"not a server hello", "160303000401000000", "|||"},
+		{"empty", "", "|||"},
+	}
+
+	for _, tt := range tests {
+		data, _ := hex.DecodeString(tt.data)
+		if got, _ := ParseServerHello(data, models.JarmOptions{}); got != tt.want {
+			t.Errorf("%s: ParseServerHello = %q, want %q", tt.name, got, tt.want)
+		}
+
+		// The exact response is fingerprinted, without the padding of a read buffer
+		pr := ProbeResult{}
+		fingerprintResponse(&pr, Target{}, data, nil, 't', nil)
+		if pr.Raw != tt.want {
+			t.Errorf("%s: fingerprintResponse raw = %q, want %q", tt.name, pr.Raw, tt.want)
+		}
+	}
+}
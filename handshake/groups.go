@@ -0,0 +1,26 @@
+package handshake
+
+import "fmt"
+
+// groupNames holds the names of the key exchange groups servers select
+var groupNames = map[uint16]string{
+	0x0017: "secp256r1",
+	0x0018: "secp384r1",
+	0x0019: "secp521r1",
+	0x001d: "x25519",
+	0x001e: "x448",
+	0x0100: "ffdhe2048",
+	0x0101: "ffdhe3072",
+	0x11eb: "SecP256r1MLKEM768",
+	0x11ec: "X25519MLKEM768",
+	0x11ed: "SecP384r1MLKEM1024",
+	0x6399: "X25519Kyber768Draft00",
+}
+
+// GroupName returns the name of a key exchange group, or its hex value if it is unknown
+func GroupName(group uint16) string {
+	if name, ok := groupNames[group]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%04x", group)
+}
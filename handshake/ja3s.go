@@ -0,0 +1,104 @@
+package handshake
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// JA3SString returns the unhashed JA3S string of the server hello
+func (sh *ServerHello) JA3SString() string {
+	exts := []string{}
+	for _, t := range sh.extensionTypes() {
+		exts = append(exts, strconv.Itoa(int(t)))
+	}
+	return fmt.Sprintf("%d,%d,%s", sh.Version, sh.CipherSuite, strings.Join(exts, "-"))
+}
+
+// JA3S returns the JA3S fingerprint of the server hello
+func (sh *ServerHello) JA3S() string {
+	sum := md5.Sum([]byte(sh.JA3SString()))
+	return hex.EncodeToString(sum[:])
+}
+
+// JA4S returns the JA4S fingerprint of the server hello
+//
+// The transport is 't' for TLS over TCP, 'q' for QUIC and 'd' for DTLS.
+func (sh *ServerHello) JA4S(transport byte) string {
+	exts := []string{}
+	for _, t := range sh.extensionTypes() {
+		exts = append(exts, fmt.Sprintf("%04x", t))
+	}
+
+	count := len(exts)
+	if count > 99 {
+		count = 99
+	}
+
+	digest := "000000000000"
+	if len(exts) > 0 {
+		sum := sha256.Sum256([]byte(strings.Join(exts, ",")))
+		digest = hex.EncodeToString(sum[:])[:12]
+	}
+
+	return fmt.Sprintf("%c%s%02d%s_%04x_%s", transport, ja4Version(sh.SelectedVersion()), count, ja4ALPN(sh.ALPN()), sh.CipherSuite, digest)
+}
+
+// extensionTypes returns the extension types of the server hello in order, leaving out GREASE values
+func (sh *ServerHello) extensionTypes() []uint16 {
+	types := []uint16{}
+	for _, e := range sh.Extensions {
+		if !isGREASE(e.Type) {
+			types = append(types, e.Type)
+		}
+	}
+	return types
+}
+
+// isGREASE reports whether a value is one of the GREASE values of RFC 8701
+func isGREASE(v uint16) bool {
+	return v&0x0f0f == 0x0a0a && v>>8 == v&0xff
+}
+
+func ja4Version(v uint16) string {
+	switch v {
+	case 0x0304:
+		return "13"
+	case 0x0303:
+		return "12"
+	case 0x0302:
+		return "11"
+	case 0x0301:
+		return "10"
+	case 0x0300:
+		return "s3"
+	case 0x0002:
+		return "s2"
+	case 0xfeff:
+		return "d1"
+	case 0xfefd:
+		return "d2"
+	case 0xfefc:
+		return "d3"
+	}
+	return "00"
+}
+
+func ja4ALPN(alpn string) string {
+	if alpn == "" {
+		return "00"
+	}
+	first, last := alpn[0], alpn[len(alpn)-1]
+	if isAlphanumeric(first) && isAlphanumeric(last) {
+		return string([]byte{first, last})
+	}
+	h := hex.EncodeToString([]byte(alpn))
+	return string([]byte{h[0], h[len(h)-1]})
+}
+
+func isAlphanumeric(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
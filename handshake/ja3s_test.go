@@ -0,0 +1,91 @@
+package handshake
+
+import "testing"
+
+func TestJA3SAndJA4S(t *testing.T) {
+	ext := func(types ...uint16) []Extension {
+		exts := []Extension{}
+		for _, t := range types {
+			exts = append(exts, Extension{Type: t})
+		}
+		return exts
+	}
+	alpn := func(proto string) Extension {
+		return Extension{Type: ExtALPN, Data: append([]byte{0x00, byte(len(proto) + 1), byte(len(proto))}, proto...)}
+	}
+
+	tests := []struct {
+		name      string
+		sh        *ServerHello
+		transport byte
+		ja3s      string
+		ja3sHash  string
+		ja4s      string
+	}{
+		{
+			// FoxIO's JA4S example, TLS 1.3 selected through supported_versions
+			"tls 1.3",
+			&ServerHello{Version: 0x0303, CipherSuite: 0x1301, Extensions: []Extension{
+				{Type: ExtKeyShare, Data: []byte{0x00, 0x1d, 0x00, 0x00}},
+				{Type: ExtSupportedVersions, Data: []byte{0x03, 0x04}},
+			}},
+			't', "771,4865,51-43", "eb1d94daa7e0344597e756a1fb6e7054", "t130200_1301_234ea6891581",
+		},
+		{
+			// FoxIO's JA4S example for TLS 1.2
+			"tls 1.2",
+			&ServerHello{Version: 0x0303, CipherSuite: 0xc030, Extensions: ext(0x0005, 0x0017, 0xff01, 0x0000)},
+			't', "771,49200,5-23-65281-0", "7d8fd34fdb13a7fff30d5a52846b6c4c", "t120400_c030_4e8089b08790",
+		},
+		{
+			"grease",
+			&ServerHello{Version: 0x0303, CipherSuite: 0xc030, Extensions: ext(0x0a0a, 0x0005, 0x0017, 0x5a5a, 0xff01, 0x0000, 0xfafa)},
+			't', "771,49200,5-23-65281-0", "7d8fd34fdb13a7fff30d5a52846b6c4c", "t120400_c030_4e8089b08790",
+		},
+		{
+			"alpn",
+			&ServerHello{Version: 0x0303, CipherSuite: 0xc02f, Extensions: []Extension{{Type: 0xff01}, alpn("h2")}},
+			't', "771,49199,65281-16", "7bee5c1d424b7e5f943b06983bb11422", "t1202h2_c02f_87b1562aab70",
+		},
+		{
+			"http/1.1 alpn",
+			&ServerHello{Version: 0x0303, CipherSuite: 0xc02f, Extensions: []Extension{{Type: 0xff01}, alpn("http/1.1")}},
+			't', "771,49199,65281-16", "7bee5c1d424b7e5f943b06983bb11422", "t1202h1_c02f_87b1562aab70",
+		},
+		{
+			"no extensions",
+			&ServerHello{Version: 0x0303, CipherSuite: 0xc02f},
+			't', "771,49199,", "174e7e4992a63f6d419626d97363adb8", "t120000_c02f_000000000000",
+		},
+		{
+			"dtls 1.2",
+			&ServerHello{Version: 0xfefd, CipherSuite: 0xc02f, Extensions: ext(0xff01, 0x0017)},
+			'd', "65277,49199,65281-23", "ae636c3cea7d3d6ff85744deadf57ebc", "dd20200_c02f_ec53b3cc8a64",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := tt.sh.JA3SString(); got != tt.ja3s {
+			t.Errorf("%s: JA3SString = %q, want %q", tt.name, got, tt.ja3s)
+		}
+		if got := tt.sh.JA3S(); got != tt.ja3sHash {
+			t.Errorf("%s: JA3S = %s, want %s", tt.name, got, tt.ja3sHash)
+		}
+		if got := tt.sh.JA4S(tt.transport); got != tt.ja4s {
+			t.Errorf("%s: JA4S = %s, want %s", tt.name, got, tt.ja4s)
+		}
+	}
+}
+
+func TestJA3SFromServerHello(t *testing.T) {
+	sh, err := ParseServerHello(unhex(t, serverHelloHex))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sh.JA3S(); got != "eb1d94daa7e0344597e756a1fb6e7054" {
+		t.Errorf("JA3S = %s", got)
+	}
+	if got := sh.JA4S('q'); got != "q130200_1301_234ea6891581" {
+		t.Errorf("JA4S = %s", got)
+	}
+}
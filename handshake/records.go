@@ -0,0 +1,24 @@
+package handshake
+
+const recordTypeAlert = 21
+
+// ParseAlert returns the level and description of an alert record at the start of a TLS response
+func ParseAlert(data []byte) (level byte, description byte, ok bool) {
+	if len(data) < 7 || data[0] != recordTypeAlert {
+		return 0, 0, false
+	}
+	return data[5], data[6], true
+}
+
+// ClientHelloSessionID returns the session ID of a client hello record built by probes.BuildProbe
+func ClientHelloSessionID(payload []byte) []byte {
+	// record header (5), handshake header (4), version (2) and random (32)
+	if len(payload) < 44 {
+		return nil
+	}
+	l := int(payload[43])
+	if len(payload) < 44+l {
+		return nil
+	}
+	return payload[44 : 44+l]
+}
@@ -0,0 +1,116 @@
+package handshake
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+// helloRetryRequestRandom is the random value of HelloRetryRequests, the SHA-256 of "HelloRetryRequest"
+var helloRetryRequestRandom = []byte{
+	0xcf, 0x21, 0xad, 0x74, 0xe5, 0x9a, 0x61, 0x11, 0xbe, 0x1d, 0x8c, 0x02, 0x1e, 0x65, 0xb8, 0x91,
+	0xc2, 0xa2, 0x11, 0x16, 0x7a, 0xbb, 0x8c, 0x5e, 0x07, 0x9e, 0x09, 0xe2, 0xc8, 0xa8, 0x33, 0x9c,
+}
+
+const (
+	recordTypeChangeCipherSpec = 20
+	typeClientHello            = 1
+)
+
+// IsHelloRetryRequest reports whether the server hello is a TLS 1.3 HelloRetryRequest
+func (sh *ServerHello) IsHelloRetryRequest() bool {
+	return bytes.Equal(sh.Random, helloRetryRequestRandom)
+}
+
+// SkipChangeCipherSpec returns a response without the ChangeCipherSpec records at its start
+//
+// TLS 1.3 servers in middlebox compatibility mode send one after a HelloRetryRequest.
+func SkipChangeCipherSpec(data []byte) []byte {
+	for len(data) >= 6 && data[0] == recordTypeChangeCipherSpec && data[1] == 3 {
+		length := int(binary.BigEndian.Uint16(data[3:5]))
+		if len(data) < 5+length {
+			break
+		}
+		data = data[5+length:]
+	}
+	return data
+}
+
+// RetryClientHello returns the second client hello of a handshake, answering a HelloRetryRequest
+//
+// The client hello record is copied, with its key shares replaced by a
+// single share for the requested group, and the cookie extension of the
+// HelloRetryRequest echoed if it sent one.
+func RetryClientHello(record []byte, group uint16, share []byte, cookie []byte) ([]byte, error) {
+	if len(record) < 9 || record[0] != recordTypeHandshake || record[5] != typeClientHello {
+		return nil, errors.New("not a client hello record")
+	}
+	body := record[9:]
+
+	// version (2), random (32) and session ID
+	offset := 34
+	if len(body) < offset+1 {
+		return nil, errors.New("client hello too short")
+	}
+	offset += 1 + int(body[offset])
+	// cipher suites
+	if len(body) < offset+2 {
+		return nil, errors.New("client hello too short")
+	}
+	offset += 2 + int(binary.BigEndian.Uint16(body[offset:offset+2]))
+	// compression methods
+	if len(body) < offset+1 {
+		return nil, errors.New("client hello too short")
+	}
+	offset += 1 + int(body[offset])
+	if len(body) < offset+2 {
+		return nil, errors.New("client hello has no extensions")
+	}
+	extEnd := offset + 2 + int(binary.BigEndian.Uint16(body[offset:offset+2]))
+	if extEnd > len(body) {
+		return nil, errors.New("client hello truncated")
+	}
+
+	keyShare := binary.BigEndian.AppendUint16(nil, uint16(len(share)+4))
+	keyShare = binary.BigEndian.AppendUint16(keyShare, group)
+	keyShare = binary.BigEndian.AppendUint16(keyShare, uint16(len(share)))
+	keyShare = append(keyShare, share...)
+
+	extensions := []byte{}
+	appendExt := func(t uint16, data []byte) {
+		extensions = binary.BigEndian.AppendUint16(extensions, t)
+		extensions = binary.BigEndian.AppendUint16(extensions, uint16(len(data)))
+		extensions = append(extensions, data...)
+	}
+
+	for ext := offset + 2; ext+4 <= extEnd; {
+		extType := binary.BigEndian.Uint16(body[ext : ext+2])
+		extLen := int(binary.BigEndian.Uint16(body[ext+2 : ext+4]))
+		if ext+4+extLen > extEnd {
+			return nil, errors.New("client hello extension truncated")
+		}
+		switch extType {
+		case ExtCookie:
+		case ExtKeyShare:
+			appendExt(ExtKeyShare, keyShare)
+			if len(cookie) > 0 {
+				appendExt(ExtCookie, cookie)
+			}
+		default:
+			appendExt(extType, body[ext+4:ext+4+extLen])
+		}
+		ext += 4 + extLen
+	}
+
+	hello := append([]byte{}, body[:offset]...)
+	hello = binary.BigEndian.AppendUint16(hello, uint16(len(extensions)))
+	hello = append(hello, extensions...)
+
+	message := []byte{typeClientHello, byte(len(hello) >> 16), byte(len(hello) >> 8), byte(len(hello))}
+	message = append(message, hello...)
+
+	// Only the initial client hello may use a record version other than TLS 1.2
+	out := []byte{recordTypeHandshake, 0x03, 0x03}
+	out = binary.BigEndian.AppendUint16(out, uint16(len(message)))
+	return append(out, message...), nil
+}
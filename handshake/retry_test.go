@@ -0,0 +1,136 @@
+package handshake
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+const (
+	// HelloRetryRequest asking for a secp256r1 share, with a cookie
+	helloRetryRequestHex = "16 0303 0042 02 00003e 0303" +
+		"cf21ad74e59a6111be1d8c021e65b891c2a211167abb8c5e079e09e2c8a8339c 00 1301 00 0016" +
+		"002b 0002 0304 0033 0002 0017 002c 0006 0004deadbeef"
+
+	// ServerHello of RFC 9001, Appendix A.3, selecting an x25519 share
+	serverHelloHex = "16 0303 005a 02 000056 0303" +
+		"eefce7f7b37ba1d1632e96677825ddf73988cfc79825df566dc5430b9a045a12 00 1301 00 002e" +
+		"0033 0024 001d 0020 9d3c940d89690b84d08a60993c144eca684d1081287c834d5311bcf32bb9da1a" +
+		"002b 0002 0304"
+
+	changeCipherSpecHex = "14 0303 0001 01"
+
+	// ClientHello with an x25519 share and a stale cookie
+	clientHelloHex = "16 0301 0067 01 000063 0303" +
+		"0000000000000000000000000000000000000000000000000000000000000000 00 0002 1301 01 00 0038" +
+		"002b 0003 020304" +
+		"0033 0026 0024 001d 0020 1111111111111111111111111111111111111111111111111111111111111111" +
+		"002c 0003 0001ff"
+)
+
+// unhex decodes a hex fixture, ignoring spaces
+func unhex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(strings.ReplaceAll(s, " ", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestIsHelloRetryRequest(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		hrr    bool
+		group  uint16
+		cookie string
+	}{
+		{"hello retry request", helloRetryRequestHex, true, 0x0017, "0004deadbeef"},
+		{"server hello", serverHelloHex, false, 0x001d, ""},
+	}
+	for _, tt := range tests {
+		sh, err := ParseServerHello(unhex(t, tt.data))
+		if err != nil {
+			t.Fatalf("%s: %s", tt.name, err)
+		}
+		if sh.IsHelloRetryRequest() != tt.hrr {
+			t.Errorf("%s: IsHelloRetryRequest = %v", tt.name, !tt.hrr)
+		}
+		if group, ok := sh.KeyShareGroup(); !ok || group != tt.group {
+			t.Errorf("%s: KeyShareGroup = %04x, %v", tt.name, group, ok)
+		}
+		if cookie, _ := sh.Extension(ExtCookie); hex.EncodeToString(cookie) != tt.cookie {
+			t.Errorf("%s: cookie = %x", tt.name, cookie)
+		}
+	}
+}
+
+func TestSkipChangeCipherSpec(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"none", helloRetryRequestHex, helloRetryRequestHex},
+		{"one", changeCipherSpecHex + helloRetryRequestHex, helloRetryRequestHex},
+		{"two", changeCipherSpecHex + changeCipherSpecHex + serverHelloHex, serverHelloHex},
+		{"only", changeCipherSpecHex, ""},
+		{"truncated", "14 0303 0002 01", "14 0303 0002 01"},
+		{"not tls", "14 0000 0001 01", "14 0000 0001 01"},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		if got := SkipChangeCipherSpec(unhex(t, tt.data)); !bytes.Equal(got, unhex(t, tt.want)) {
+			t.Errorf("%s: SkipChangeCipherSpec = %x", tt.name, got)
+		}
+	}
+}
+
+func TestRetryClientHello(t *testing.T) {
+	share := unhex(t, "aabbccdd")
+	tests := []struct {
+		name   string
+		cookie string
+		want   string
+	}{
+		{
+			"cookie", "0004deadbeef",
+			"16 0303 004e 01 00004a 0303" +
+				"0000000000000000000000000000000000000000000000000000000000000000 00 0002 1301 01 00 001f" +
+				"002b 0003 020304 0033 000a 0008 0017 0004 aabbccdd 002c 0006 0004deadbeef",
+		},
+		{
+			"no cookie", "",
+			"16 0303 0044 01 000040 0303" +
+				"0000000000000000000000000000000000000000000000000000000000000000 00 0002 1301 01 00 0015" +
+				"002b 0003 020304 0033 000a 0008 0017 0004 aabbccdd",
+		},
+	}
+	for _, tt := range tests {
+		got, err := RetryClientHello(unhex(t, clientHelloHex), 0x0017, share, unhex(t, tt.cookie))
+		if err != nil {
+			t.Fatalf("%s: %s", tt.name, err)
+		}
+		if !bytes.Equal(got, unhex(t, tt.want)) {
+			t.Errorf("%s: RetryClientHello = %x", tt.name, got)
+		}
+	}
+
+	invalid := []struct {
+		name string
+		data string
+	}{
+		{"server hello", serverHelloHex},
+		{"truncated", "16 0301 0067 01 000063 0303 00000000"},
+		{"no extensions", "16 0301 002d 01 000029 0303 0000000000000000000000000000000000000000000000000000000000000000 00 0002 1301 01 00"},
+		{"truncated extensions", strings.Replace(clientHelloHex, "0038", "0039", 1)},
+		{"empty", ""},
+	}
+	for _, tt := range invalid {
+		if _, err := RetryClientHello(unhex(t, tt.data), 0x0017, share, nil); err == nil {
+			t.Errorf("%s: RetryClientHello accepted", tt.name)
+		}
+	}
+}
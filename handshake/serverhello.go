@@ -0,0 +1,140 @@
+package handshake
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// ServerHello is a parsed server hello message
+type ServerHello struct {
+	Version     uint16
+	Random      []byte
+	SessionID   []byte
+	CipherSuite uint16
+	Compression uint8
+	Extensions  []Extension
+}
+
+// Extension is a single server hello extension
+type Extension struct {
+	Type uint16
+	Data []byte
+}
+
+const (
+	recordTypeHandshake = 22
+	typeServerHello     = 2
+)
+
+// Extension types inspected by the parser
+const (
+	ExtServerName        = 0x0000
+	ExtSupportedGroups   = 0x000a
+	ExtALPN              = 0x0010
+	ExtSupportedVersions = 0x002b
+	ExtCookie            = 0x002c
+	ExtKeyShare          = 0x0033
+)
+
+// ParseServerHello parses the server hello at the start of a TLS response
+func ParseServerHello(data []byte) (*ServerHello, error) {
+	if len(data) < 9 || data[0] != recordTypeHandshake {
+		return nil, errors.New("not a handshake record")
+	}
+	if data[5] != typeServerHello {
+		return nil, errors.New("not a server hello")
+	}
+
+	length := int(data[6])<<16 | int(data[7])<<8 | int(data[8])
+	body := data[9:]
+	if len(body) > length {
+		body = body[:length]
+	}
+	return ParseServerHelloBody(body)
+}
+
+// ParseServerHelloBody parses a server hello message without its record and handshake headers
+func ParseServerHelloBody(body []byte) (*ServerHello, error) {
+	if len(body) < 35 {
+		return nil, errors.New("server hello too short")
+	}
+
+	sh := &ServerHello{
+		Version: binary.BigEndian.Uint16(body[0:2]),
+		Random:  body[2:34],
+	}
+
+	sidLen := int(body[34])
+	offset := 35 + sidLen
+	if len(body) < offset+3 {
+		return nil, errors.New("server hello too short")
+	}
+	sh.SessionID = body[35:offset]
+	sh.CipherSuite = binary.BigEndian.Uint16(body[offset : offset+2])
+	sh.Compression = body[offset+2]
+	offset += 3
+
+	// Extensions are optional
+	if len(body) < offset+2 {
+		return sh, nil
+	}
+
+	extEnd := offset + 2 + int(binary.BigEndian.Uint16(body[offset:offset+2]))
+	if extEnd > len(body) {
+		extEnd = len(body)
+	}
+	offset += 2
+
+	for offset+4 <= extEnd {
+		extType := binary.BigEndian.Uint16(body[offset : offset+2])
+		extLen := int(binary.BigEndian.Uint16(body[offset+2 : offset+4]))
+		offset += 4
+		if offset+extLen > extEnd {
+			break
+		}
+		sh.Extensions = append(sh.Extensions, Extension{Type: extType, Data: body[offset : offset+extLen]})
+		offset += extLen
+	}
+
+	return sh, nil
+}
+
+// Extension returns the data of the given extension type
+func (sh *ServerHello) Extension(t uint16) ([]byte, bool) {
+	for _, e := range sh.Extensions {
+		if e.Type == t {
+			return e.Data, true
+		}
+	}
+	return nil, false
+}
+
+// SelectedVersion returns the negotiated version, taking supported_versions into account
+func (sh *ServerHello) SelectedVersion() uint16 {
+	if v, ok := sh.Extension(ExtSupportedVersions); ok && len(v) == 2 {
+		return binary.BigEndian.Uint16(v)
+	}
+	return sh.Version
+}
+
+// ALPN returns the selected application protocol, if any
+func (sh *ServerHello) ALPN() string {
+	v, ok := sh.Extension(ExtALPN)
+	if !ok || len(v) < 3 {
+		return ""
+	}
+	l := int(v[2])
+	if len(v) < 3+l {
+		return ""
+	}
+	return string(v[3 : 3+l])
+}
+
+// KeyShareGroup returns the group of the key share the server selected or, for a HelloRetryRequest, requested
+func (sh *ServerHello) KeyShareGroup() (uint16, bool) {
+	ks, ok := sh.Extension(ExtKeyShare)
+	if !ok || len(ks) < 2 {
+		return 0, false
+	}
+	return binary.BigEndian.Uint16(ks[:2]), true
+}
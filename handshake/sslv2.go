@@ -0,0 +1,93 @@
+package handshake
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// SSLv2 message types
+const (
+	sslv2Error       = 0
+	sslv2ServerHello = 4
+)
+
+// SSLv2 cipher kinds, as offered in SSLv2 client hellos
+var SSLv2CipherSpecs = []uint32{
+	0x010080, // SSL_CK_RC4_128_WITH_MD5
+	0x020080, // SSL_CK_RC4_128_EXPORT40_WITH_MD5
+	0x030080, // SSL_CK_RC2_128_CBC_WITH_MD5
+	0x040080, // SSL_CK_RC2_128_CBC_EXPORT40_WITH_MD5
+	0x050080, // SSL_CK_IDEA_128_CBC_WITH_MD5
+	0x060040, // SSL_CK_DES_64_CBC_WITH_MD5
+	0x0700c0, // SSL_CK_DES_192_EDE3_CBC_WITH_MD5
+}
+
+// SSLv2ServerHello is a parsed SSLv2 SERVER-HELLO message
+type SSLv2ServerHello struct {
+	SessionIDHit    bool
+	CertificateType byte
+	Version         uint16
+	Certificate     []byte
+	CipherSpecs     []uint32
+	ConnectionID    []byte
+}
+
+// ParseSSLv2ServerHello parses the SSLv2 server hello at the start of a response
+func ParseSSLv2ServerHello(data []byte) (*SSLv2ServerHello, error) {
+	body, err := sslv2Message(data)
+	if err != nil {
+		return nil, err
+	}
+	if body[0] != sslv2ServerHello {
+		return nil, errors.New("not an SSLv2 server hello")
+	}
+	if len(body) < 11 {
+		return nil, errors.New("SSLv2 server hello too short")
+	}
+
+	sh := &SSLv2ServerHello{
+		SessionIDHit:    body[1] != 0,
+		CertificateType: body[2],
+		Version:         binary.BigEndian.Uint16(body[3:5]),
+	}
+	certLen := int(binary.BigEndian.Uint16(body[5:7]))
+	specsLen := int(binary.BigEndian.Uint16(body[7:9]))
+	connLen := int(binary.BigEndian.Uint16(body[9:11]))
+	if specsLen%3 != 0 || len(body) < 11+certLen+specsLen+connLen {
+		return nil, errors.New("SSLv2 server hello truncated")
+	}
+
+	offset := 11
+	sh.Certificate = body[offset : offset+certLen]
+	offset += certLen
+	for i := 0; i < specsLen; i += 3 {
+		spec := body[offset+i : offset+i+3]
+		sh.CipherSpecs = append(sh.CipherSpecs, uint32(spec[0])<<16|uint32(spec[1])<<8|uint32(spec[2]))
+	}
+	offset += specsLen
+	sh.ConnectionID = body[offset : offset+connLen]
+	return sh, nil
+}
+
+// ParseSSLv2Error returns the error code of an SSLv2 ERROR message at the start of a response
+func ParseSSLv2Error(data []byte) (uint16, bool) {
+	body, err := sslv2Message(data)
+	if err != nil || body[0] != sslv2Error || len(body) < 3 {
+		return 0, false
+	}
+	return binary.BigEndian.Uint16(body[1:3]), true
+}
+
+// sslv2Message returns the message of the SSLv2 record at the start of a response
+func sslv2Message(data []byte) ([]byte, error) {
+	// Server hellos and errors are never padded, so only two byte headers are accepted
+	if len(data) < 3 || data[0]&0x80 == 0 {
+		return nil, errors.New("not an SSLv2 record")
+	}
+	length := int(binary.BigEndian.Uint16(data[0:2]) & 0x7fff)
+	offset := 2
+	if length == 0 || len(data) < offset+length {
+		return nil, errors.New("SSLv2 record truncated")
+	}
+	return data[offset : offset+length], nil
+}
@@ -0,0 +1,65 @@
+package handshake
+
+import (
+	"bytes"
+	"testing"
+)
+
+// SSLv2 SERVER-HELLO with a 4 byte X.509 certificate, two cipher specs and a 16 byte connection ID
+const sslv2ServerHelloHex = "8025 04 00 01 0002 0004 0006 0010 30820102 010080 0700c0 000102030405060708090a0b0c0d0e0f"
+
+func TestParseSSLv2ServerHello(t *testing.T) {
+	sh, err := ParseSSLv2ServerHello(unhex(t, sslv2ServerHelloHex))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sh.SessionIDHit || sh.CertificateType != 1 || sh.Version != 0x0002 {
+		t.Errorf("server hello = %+v", sh)
+	}
+	if !bytes.Equal(sh.Certificate, unhex(t, "30820102")) || !bytes.Equal(sh.ConnectionID, unhex(t, "000102030405060708090a0b0c0d0e0f")) {
+		t.Errorf("certificate %x, connection ID %x", sh.Certificate, sh.ConnectionID)
+	}
+	if len(sh.CipherSpecs) != 2 || sh.CipherSpecs[0] != 0x010080 || sh.CipherSpecs[1] != 0x0700c0 {
+		t.Errorf("cipher specs = %06x", sh.CipherSpecs)
+	}
+
+	invalid := []struct {
+		name string
+		data string
+	}{
+		{"truncated record", sslv2ServerHelloHex[:len(sslv2ServerHelloHex)-2]},
+		{"truncated header", "800a 04 00 01 0002 0004 0006"},
+		{"lengths past the record", "8025 04 00 01 0002 0010 0006 0010 30820102 010080 0700c0 000102030405060708090a0b0c0d0e0f"},
+		{"partial cipher spec", "8024 04 00 01 0002 0004 0005 0010 30820102 010080 0700 000102030405060708090a0b0c0d0e0f"},
+		{"error", "8003 00 0001"},
+		{"tls record", "160303003b020000370303"},
+		{"telnet", "fffb01fffb03fffd18"},
+		{"empty record", "8000"},
+		{"empty", ""},
+	}
+	for _, tt := range invalid {
+		if sh, err := ParseSSLv2ServerHello(unhex(t, tt.data)); err == nil {
+			t.Errorf("%s: ParseSSLv2ServerHello = %+v", tt.name, sh)
+		}
+	}
+}
+
+func TestParseSSLv2Error(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		code uint16
+		ok   bool
+	}{
+		{"no cipher", "8003 00 0001", 1, true},
+		{"bad certificate", "8003 00 0004", 4, true},
+		{"server hello", sslv2ServerHelloHex, 0, false},
+		{"truncated", "8003 00 00", 0, false},
+		{"tls alert", "15030300020228", 0, false},
+	}
+	for _, tt := range tests {
+		if code, ok := ParseSSLv2Error(unhex(t, tt.data)); code != tt.code || ok != tt.ok {
+			t.Errorf("%s: ParseSSLv2Error = %d, %v", tt.name, code, ok)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package jarm
+
+import "fmt"
+
+// Classification is the suggested relation between two compared hashes
+type Classification string
+
+const (
+	Identical Classification = "identical"
+	SameStack Classification = "same-stack"
+	Unrelated Classification = "unrelated"
+)
+
+// SameStackThreshold is the default minimum score for two hashes to be considered the same TLS stack
+const SameStackThreshold = 0.7
+
+// Similarity is the result of comparing two JARM hashes
+type Similarity struct {
+	// Score is the fraction of matching components, from 0 to 1
+	Score float64
+	// Distance is the number of differing components
+	Distance        int
+	DifferingProbes []string
+	DigestMatch     bool
+	Classification  Classification
+}
+
+// Compare compares two JARM hashes probe by probe
+//
+// The cipher and version of every probe is a component of its own, while
+// the extension digest is treated as a single component that either matches or not.
+// Both hashes must have the same prefix, as hashes of different probe sets are not comparable.
+func Compare(a, b string) (Similarity, error) {
+	return CompareWithThreshold(a, b, SameStackThreshold)
+}
+
+// CompareWithThreshold compares two JARM hashes, classifying them as the same
+// stack if their score is at least the threshold
+func CompareWithThreshold(a, b string, threshold float64) (Similarity, error) {
+	da, err := DecodeHash(a)
+	if err != nil {
+		return Similarity{}, err
+	}
+	db, err := DecodeHash(b)
+	if err != nil {
+		return Similarity{}, err
+	}
+	if da.Prefix != db.Prefix {
+		return Similarity{}, fmt.Errorf("hashes of different probe sets: %q and %q", da.Prefix, db.Prefix)
+	}
+
+	sim := Similarity{
+		DifferingProbes: []string{},
+		DigestMatch:     da.ExtensionDigest == db.ExtensionDigest,
+	}
+
+	total := len(da.Components)*2 + 1
+	for i, ca := range da.Components {
+		cb := db.Components[i]
+		differs := false
+		if ca.CipherIndex != cb.CipherIndex {
+			sim.Distance++
+			differs = true
+		}
+		if ca.Version != cb.Version {
+			sim.Distance++
+			differs = true
+		}
+		if differs {
+			sim.DifferingProbes = append(sim.DifferingProbes, ca.Probe)
+		}
+	}
+	if !sim.DigestMatch {
+		sim.Distance++
+	}
+
+	sim.Score = float64(total-sim.Distance) / float64(total)
+
+	switch {
+	case sim.Distance == 0:
+		sim.Classification = Identical
+	case sim.Score >= threshold:
+		sim.Classification = SameStack
+	default:
+		sim.Classification = Unrelated
+	}
+
+	return sim, nil
+}
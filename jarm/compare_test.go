@@ -0,0 +1,35 @@
+package jarm
+
+import "testing"
+
+func TestCompareWithThreshold(t *testing.T) {
+	a := "29d29d00029d29d00041d43d00041d2aa5ce6a70de7ba95aef77a77b00a0af"
+	b := "29d29d00029d29d00041d41d00041d2aa5ce6a70de7ba95aef77a77b00a0af"
+	c := "2ad2ad0002ad2ad0002ad2ad2ad2ad00000000000000000000000000000000"
+
+	tests := []struct {
+		a, b      string
+		threshold float64
+		distance  int
+		class     Classification
+	}{
+		{a, a, SameStackThreshold, 0, Identical},
+		{a, b, SameStackThreshold, 1, SameStack},
+		{a, b, 0.99, 1, Unrelated},
+		{a, c, SameStackThreshold, 13, Unrelated},
+	}
+
+	for _, tt := range tests {
+		sim, err := CompareWithThreshold(tt.a, tt.b, tt.threshold)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sim.Distance != tt.distance || sim.Classification != tt.class {
+			t.Errorf("CompareWithThreshold(%s, %s, %v) = %d %s, want %d %s", tt.a, tt.b, tt.threshold, sim.Distance, sim.Classification, tt.distance, tt.class)
+		}
+	}
+
+	if _, err := Compare(a, "not a hash"); err == nil {
+		t.Error("Compare accepted an invalid hash")
+	}
+}
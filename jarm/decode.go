@@ -0,0 +1,152 @@
+package jarm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/TheGejr/gojarm/ciphers"
+	"github.com/TheGejr/gojarm/models"
+	"github.com/TheGejr/gojarm/probes"
+)
+
+// HashLength is the length of a JARM hash
+const HashLength = 62
+
+// PrefixLength is the length of the cipher and version part of a JARM hash
+const PrefixLength = 30
+
+// Component is the decoded part of a JARM hash belonging to a single probe
+type Component struct {
+	Probe       string
+	CipherIndex int
+	Cipher      []byte
+	CipherName  string
+	Version     string
+}
+
+// Responded reports whether the server answered the probe with a server hello
+func (c Component) Responded() bool {
+	return c.CipherIndex != 0
+}
+
+// Decoded is a JARM hash split into its per-probe components
+type Decoded struct {
+	Hash string
+	// Prefix is the prefix of the hash, see SplitPrefix
+	Prefix          string
+	Components      []Component
+	ExtensionDigest string
+}
+
+// DecodeHash splits a JARM hash into the components of the ten probes
+//
+// Extended, DTLS and QUIC hashes are decoded as well, their components are named after their own probes.
+func DecodeHash(hash string) (Decoded, error) {
+	prefix, bare := SplitPrefix(hash)
+	names, err := probeSet(prefix)
+	if err != nil {
+		return Decoded{}, err
+	}
+	if _, err := ParseHash(bare); err != nil {
+		return Decoded{}, err
+	}
+	hash = strings.ToLower(bare)
+
+	decoded := Decoded{
+		Hash:            prefix + hash,
+		Prefix:          prefix,
+		ExtensionDigest: hash[PrefixLength:],
+	}
+
+	for i := 0; i < PrefixLength; i += 3 {
+		idx, err := strconv.ParseUint(hash[i:i+2], 16, 8)
+		if err != nil {
+			return Decoded{}, fmt.Errorf("invalid cipher index: %s", hash[i:i+2])
+		}
+
+		comp := Component{
+			Probe:       names[i/3].Name,
+			CipherIndex: int(idx),
+		}
+
+		if cipher, ok := ciphers.CipherFromIndex(hash[i : i+2]); ok {
+			comp.Cipher = cipher
+			comp.CipherName = ciphers.CipherName(cipher)
+		}
+
+		if hash[i+2] != '0' {
+			comp.Version = ciphers.VersionName(hash[i+2])
+			if comp.Version == "" {
+				return Decoded{}, fmt.Errorf("invalid version: %c", hash[i+2])
+			}
+		}
+
+		// TLS 1.3 servers answer with the legacy 1.2 version, the cipher gives it away
+		if len(comp.Cipher) == 2 && comp.Cipher[0] == 0x13 {
+			comp.Version = "TLS 1.3"
+		}
+
+		decoded.Components = append(decoded.Components, comp)
+	}
+
+	return decoded, nil
+}
+
+// probeSet returns the probes of the hashes with a prefix
+func probeSet(prefix string) ([]models.JarmOptions, error) {
+	switch prefix {
+	case "", ExtendedPrefix:
+		return probes.GetProbes("", 0), nil
+	case DTLSPrefix:
+		return probes.GetDTLSProbes("", 0), nil
+	case QUICPrefix:
+		return probes.GetQUICProbes("", 0), nil
+	}
+	return nil, fmt.Errorf("unsupported hash prefix: %s", prefix)
+}
+
+// Explain returns a plain-language summary of the decoded hash
+func (d Decoded) Explain() string {
+	versions := []string{}
+	silent := []string{}
+	preferred := ""
+
+	for _, c := range d.Components {
+		if !c.Responded() {
+			silent = append(silent, c.Probe)
+			continue
+		}
+		if preferred == "" && c.CipherName != "" {
+			preferred = c.CipherName
+		}
+		if c.Version != "" && !contains(versions, c.Version) {
+			versions = append(versions, c.Version)
+		}
+	}
+
+	if len(silent) == len(d.Components) {
+		return "no response to any probe"
+	}
+
+	parts := []string{}
+	for _, v := range versions {
+		parts = append(parts, v+" supported")
+	}
+	if preferred != "" {
+		parts = append(parts, "prefers "+preferred)
+	}
+	if len(silent) > 0 {
+		parts = append(parts, "no response to "+strings.Join(silent, ", "))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,50 @@
+package jarm
+
+import "testing"
+
+func TestDecodeHashPrefixes(t *testing.T) {
+	const hash = "29d29d00029d29d00041d43d00041d2aa5ce6a70de7ba95aef77a77b00a0af"
+
+	tests := []struct {
+		hash   string
+		prefix string
+		probe  string
+		ok     bool
+	}{
+		{hash, "", "TLS 1.2 forward", true},
+		{ExtendedPrefix + hash, ExtendedPrefix, "TLS 1.2 forward", true},
+		{DTLSPrefix + hash, DTLSPrefix, "DTLS 1.2 forward", true},
+		{QUICPrefix + hash, QUICPrefix, "QUIC TLS 1.2 forward", true},
+		{LegacyPrefix + hash[:50], "", "", false},
+		{DTLSPrefix + hash[:61], "", "", false},
+	}
+
+	for _, tt := range tests {
+		d, err := DecodeHash(tt.hash)
+		if (err == nil) != tt.ok {
+			t.Errorf("DecodeHash(%s): err = %v", tt.hash, err)
+			continue
+		}
+		if !tt.ok {
+			continue
+		}
+		if d.Prefix != tt.prefix || d.Hash != tt.hash || d.Components[0].Probe != tt.probe {
+			t.Errorf("DecodeHash(%s) = %q %q %q", tt.hash, d.Prefix, d.Hash, d.Components[0].Probe)
+		}
+		if len(d.Components) != 10 || d.Components[0].CipherName == "" {
+			t.Errorf("DecodeHash(%s) components = %+v", tt.hash, d.Components)
+		}
+	}
+
+	if sim, err := Compare(DTLSPrefix+hash, DTLSPrefix+hash); err != nil || sim.Classification != Identical {
+		t.Errorf("Compare of DTLS hashes = %+v, %v", sim, err)
+	}
+	for _, other := range []string{DTLSPrefix + hash, QUICPrefix + hash} {
+		if _, err := Compare(hash, other); err == nil {
+			t.Errorf("Compare accepted hashes of different probe sets: %s", other)
+		}
+	}
+	if _, err := Compare(DTLSPrefix+hash, QUICPrefix+hash); err == nil {
+		t.Error("Compare accepted DTLS and QUIC hashes")
+	}
+}
@@ -0,0 +1,92 @@
+package jarm
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Hash is a JARM hash in its compact binary form
+//
+// Every character of a JARM hash is a hex digit, so the 62 characters are
+// stored as 31 bytes. The zero value is the empty hash.
+type Hash [HashLength / 2]byte
+
+// versionAlphabet holds the valid version characters of a JARM hash
+const versionAlphabet = "0abcde"
+
+// ParseHash parses and validates a JARM hash
+//
+// Prefixed hashes are rejected, their prefix has to be split off with SplitPrefix first.
+func ParseHash(s string) (Hash, error) {
+	h := Hash{}
+	if len(s) != HashLength {
+		return h, fmt.Errorf("invalid JARM hash length: %d", len(s))
+	}
+
+	s = strings.ToLower(s)
+	for i := 2; i < PrefixLength; i += 3 {
+		if !strings.ContainsRune(versionAlphabet, rune(s[i])) {
+			return h, fmt.Errorf("invalid version: %c", s[i])
+		}
+	}
+
+	if _, err := hex.Decode(h[:], []byte(s)); err != nil {
+		return Hash{}, errors.New("invalid JARM hash: not hex")
+	}
+	return h, nil
+}
+
+// String returns the hash in its usual 62-character form
+func (h Hash) String() string {
+	return hex.EncodeToString(h[:])
+}
+
+// IsZero reports whether the hash is the empty hash, given to servers that did not respond
+func (h Hash) IsZero() bool {
+	return h == Hash{}
+}
+
+// CipherPrefix returns the 30-character cipher and version part of the hash
+func (h Hash) CipherPrefix() string {
+	return h.String()[:PrefixLength]
+}
+
+// ExtensionDigest returns the 32-character ALPN and extension digest of the hash
+func (h Hash) ExtensionDigest() string {
+	return h.String()[PrefixLength:]
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (h Hash) MarshalText() ([]byte, error) {
+	return []byte(h.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (h *Hash) UnmarshalText(text []byte) error {
+	parsed, err := ParseHash(string(text))
+	if err != nil {
+		return err
+	}
+	*h = parsed
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler
+func (h Hash) MarshalBinary() ([]byte, error) {
+	return append([]byte{}, h[:]...), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler
+func (h *Hash) UnmarshalBinary(data []byte) error {
+	if len(data) != len(h) {
+		return fmt.Errorf("invalid binary JARM hash length: %d", len(data))
+	}
+	parsed, err := ParseHash(hex.EncodeToString(data))
+	if err != nil {
+		return err
+	}
+	*h = parsed
+	return nil
+}
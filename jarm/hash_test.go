@@ -0,0 +1,132 @@
+package jarm
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const testHash = "27d40d40d29d40d1dc42d43d00041d4689ee210389f4f6b4b5b1b93f92252d"
+
+func TestParseHash(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+		ok   bool
+	}{
+		{"valid", testHash, testHash, true},
+		{"upper case", strings.ToUpper(testHash), testHash, true},
+		{"zero", strings.Repeat("0", HashLength), strings.Repeat("0", HashLength), true},
+		{"too short", testHash[:61], "", false},
+		{"too long", testHash + "0", "", false},
+		{"empty", "", "", false},
+		{"not hex", testHash[:40] + "g" + testHash[41:], "", false},
+		{"invalid version", testHash[:2] + "f" + testHash[3:], "", false},
+		{"extended prefix", ExtendedPrefix + testHash, "", false},
+		{"dtls prefix", DTLSPrefix + testHash, "", false},
+		{"legacy prefix", LegacyPrefix + testHash[:50], "", false},
+	}
+
+	for _, tt := range tests {
+		h, err := ParseHash(tt.in)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: ParseHash error = %v", tt.name, err)
+			continue
+		}
+		if tt.ok && h.String() != tt.want {
+			t.Errorf("%s: ParseHash = %s, want %s", tt.name, h, tt.want)
+		}
+		if !tt.ok && !h.IsZero() {
+			t.Errorf("%s: ParseHash returned %s along with an error", tt.name, h)
+		}
+	}
+
+	// Prefixed hashes parse once their prefix is split off
+	if h, err := ParseHash(StripPrefix(QUICPrefix + testHash)); err != nil || h.String() != testHash {
+		t.Errorf("ParseHash of a stripped hash = %s, %v", h, err)
+	}
+}
+
+func TestHashParts(t *testing.T) {
+	h, _ := ParseHash(testHash)
+	if h.CipherPrefix() != testHash[:PrefixLength] || h.ExtensionDigest() != testHash[PrefixLength:] {
+		t.Errorf("parts = %s, %s", h.CipherPrefix(), h.ExtensionDigest())
+	}
+	if h.IsZero() || !(Hash{}).IsZero() {
+		t.Errorf("IsZero is wrong")
+	}
+}
+
+func TestHashText(t *testing.T) {
+	h, _ := ParseHash(testHash)
+	text, err := h.MarshalText()
+	if err != nil || string(text) != testHash {
+		t.Fatalf("MarshalText = %s, %v", text, err)
+	}
+
+	var parsed Hash
+	if err := parsed.UnmarshalText([]byte(strings.ToUpper(testHash))); err != nil || parsed != h {
+		t.Errorf("UnmarshalText = %s, %v", parsed, err)
+	}
+	for _, in := range []string{testHash[:61], ExtendedPrefix + testHash, "zz" + testHash[2:]} {
+		parsed := h
+		if err := parsed.UnmarshalText([]byte(in)); err == nil || parsed != h {
+			t.Errorf("UnmarshalText(%q) = %s, %v", in, parsed, err)
+		}
+	}
+
+	// JSON uses the text form
+	type entry struct {
+		Hash Hash `json:"hash"`
+	}
+	data, err := json.Marshal(entry{Hash: h})
+	if err != nil || string(data) != `{"hash":"`+testHash+`"}` {
+		t.Fatalf("json.Marshal = %s, %v", data, err)
+	}
+	decoded := entry{}
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.Hash != h {
+		t.Errorf("json.Unmarshal = %s, %v", decoded.Hash, err)
+	}
+	if err := json.Unmarshal([]byte(`{"hash":"`+DTLSPrefix+testHash+`"}`), &decoded); err == nil {
+		t.Errorf("json.Unmarshal accepted a prefixed hash")
+	}
+}
+
+func TestHashBinary(t *testing.T) {
+	h, _ := ParseHash(testHash)
+	data, err := h.MarshalBinary()
+	if err != nil || len(data) != HashLength/2 || !bytes.Equal(data, h[:]) {
+		t.Fatalf("MarshalBinary = %x, %v", data, err)
+	}
+
+	// The marshaled bytes are a copy
+	data[0] ^= 0xff
+	if h.String() != testHash {
+		t.Errorf("MarshalBinary shares its bytes with the hash")
+	}
+	data[0] ^= 0xff
+
+	var parsed Hash
+	if err := parsed.UnmarshalBinary(data); err != nil || parsed != h {
+		t.Errorf("UnmarshalBinary = %s, %v", parsed, err)
+	}
+
+	invalid := []struct {
+		name string
+		data []byte
+	}{
+		{"short", data[:30]},
+		{"long", append(append([]byte{}, data...), 0)},
+		{"empty", nil},
+		// the third character, the first version, becomes 'f'
+		{"invalid version", append([]byte{data[0], 0xf0 | data[1]&0x0f}, data[2:]...)},
+	}
+	for _, tt := range invalid {
+		parsed := h
+		if err := parsed.UnmarshalBinary(tt.data); err == nil || parsed != h {
+			t.Errorf("%s: UnmarshalBinary = %s, %v", tt.name, parsed, err)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package jarm
+
+import "strings"
+
+// Prefixes of the hashes that are not comparable to standard JARM hashes
+//
+// Every prefix is "j", a letter naming the variant, the version of the
+// variant and "_". The version is bumped whenever the probes or the features
+// folded into the hash change, so hashes of different versions never match.
+const (
+	// ExtendedPrefix marks extended hashes, which fold more server hello features into the digest
+	ExtendedPrefix = "jx1_"
+	// LegacyPrefix marks hashes of the legacy SSLv2, SSLv3 and TLS 1.0 probe set
+	LegacyPrefix = "jl1_"
+	// DTLSPrefix marks hashes of the DTLS probe set
+	DTLSPrefix = "jd1_"
+	// QUICPrefix marks hashes of the QUIC probe set
+	QUICPrefix = "jq1_"
+)
+
+var prefixes = []string{ExtendedPrefix, LegacyPrefix, DTLSPrefix, QUICPrefix}
+
+// SplitPrefix splits a hash into its known prefix, empty for standard JARM hashes, and the hash without it
+func SplitPrefix(hash string) (string, string) {
+	for _, prefix := range prefixes {
+		if len(hash) >= len(prefix) && strings.EqualFold(hash[:len(prefix)], prefix) {
+			return prefix, hash[len(prefix):]
+		}
+	}
+	return "", hash
+}
+
+// StripPrefix returns a hash without its known prefix
+func StripPrefix(hash string) string {
+	_, bare := SplitPrefix(hash)
+	return bare
+}
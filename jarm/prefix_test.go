@@ -0,0 +1,29 @@
+package jarm
+
+import "testing"
+
+func TestSplitPrefix(t *testing.T) {
+	const hash = "29d29d00029d29d00041d43d00041d2aa5ce6a70de7ba95aef77a77b00a0af"
+
+	tests := []struct {
+		in, prefix, bare string
+	}{
+		{hash, "", hash},
+		{ExtendedPrefix + hash, ExtendedPrefix, hash},
+		{LegacyPrefix + hash[:50], LegacyPrefix, hash[:50]},
+		{DTLSPrefix + hash, DTLSPrefix, hash},
+		{"JQ1_" + hash, QUICPrefix, hash},
+		{"dtls1_" + hash, "", "dtls1_" + hash},
+		{"", "", ""},
+	}
+
+	for _, tt := range tests {
+		prefix, bare := SplitPrefix(tt.in)
+		if prefix != tt.prefix || bare != tt.bare {
+			t.Errorf("SplitPrefix(%q) = %q, %q, want %q, %q", tt.in, prefix, bare, tt.prefix, tt.bare)
+		}
+		if got := StripPrefix(tt.in); got != tt.bare {
+			t.Errorf("StripPrefix(%q) = %q", tt.in, got)
+		}
+	}
+}
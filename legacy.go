@@ -0,0 +1,99 @@
+package gojarm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/TheGejr/gojarm/ciphers"
+	"github.com/TheGejr/gojarm/handshake"
+	"github.com/TheGejr/gojarm/jarm"
+	"github.com/TheGejr/gojarm/probes"
+)
+
+// sslv2Version is the version component of SSLv2 server hellos in a raw legacy hash
+const sslv2Version = "0002"
+
+// ParseSSLv2ServerHello returns the raw legacy fingerprint for an SSLv2 server hello response
+//
+// The components are the first cipher spec the server supports, the SSLv2
+// version, all supported cipher specs and the certificate type.
+func ParseSSLv2ServerHello(data []byte) string {
+	sh, err := handshake.ParseSSLv2ServerHello(data)
+	if err != nil || len(sh.CipherSpecs) == 0 {
+		return "|||"
+	}
+
+	specs := []string{}
+	for _, spec := range sh.CipherSpecs {
+		specs = append(specs, fmt.Sprintf("%06x", spec))
+	}
+	return fmt.Sprintf("%s|%s|%s|%02x", specs[0], sslv2Version, strings.Join(specs, "-"), sh.CertificateType)
+}
+
+// RawHashToLegacyHash converts a raw legacy hash to a legacy hash
+//
+// TLS and SSLv3 probes are encoded like standard JARM probes, SSLv2 server
+// hellos as the index of their first cipher kind followed by "s".
+func RawHashToLegacyHash(raw string) string {
+	probeCount := len(strings.Split(raw, ","))
+	zero := jarm.LegacyPrefix + strings.Repeat("0", probeCount*3+32)
+
+	fhash := ""
+	alpex := ""
+	empty := true
+	for _, probe := range strings.Split(raw, ",") {
+		comp := strings.Split(probe, "|")
+		if len(comp) != 4 {
+			return zero
+		}
+		if probe != "|||" {
+			empty = false
+		}
+		if comp[1] == sslv2Version {
+			fhash = fhash + sslv2CipherIndex(comp[0]) + "s"
+		} else {
+			fhash = fhash + ciphers.ExtractCipherBytes(comp[0])
+			fhash = fhash + ciphers.ExtractVersionByte(comp[1])
+		}
+		alpex = alpex + comp[2]
+		alpex = alpex + comp[3]
+	}
+	if empty {
+		return zero
+	}
+	hash256 := sha256.Sum256([]byte(alpex))
+	fhash += hex.EncodeToString(hash256[:])[0:32]
+	return jarm.LegacyPrefix + fhash
+}
+
+// sslv2CipherIndex converts an SSLv2 cipher spec to an index of the known SSLv2 cipher kinds
+func sslv2CipherIndex(spec string) string {
+	for i, known := range handshake.SSLv2CipherSpecs {
+		if fmt.Sprintf("%06x", known) == spec {
+			return fmt.Sprintf("%.2x", i+1)
+		}
+	}
+	return "ff"
+}
+
+// legacyFingerprint sends the legacy probes to the target
+func legacyFingerprint(t Target) (string, []ProbeResult, error) {
+	raw := []string{}
+	probeResults := []ProbeResult{}
+
+	for _, probe := range probes.GetLegacyProbes(t.Host, t.Port) {
+		pr, err := probeTarget(t, probe)
+		if err != nil {
+			return "", nil, err
+		}
+		if pr.Raw == "|||" {
+			pr.Raw = ParseSSLv2ServerHello(pr.Response)
+		}
+		raw = append(raw, pr.Raw)
+		probeResults = append(probeResults, pr)
+	}
+
+	return RawHashToLegacyHash(strings.Join(raw, ",")), probeResults, nil
+}
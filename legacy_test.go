@@ -0,0 +1,131 @@
+package gojarm
+
+import (
+	"encoding/hex"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/TheGejr/gojarm/jarm"
+)
+
+const (
+	// SSLv2 SERVER-HELLO selecting RC4_128_WITH_MD5 and DES_192_EDE3_CBC_WITH_MD5, with a 4 byte X.509 certificate
+	legacySSLv2ServerHello = "8025040001000200040006001030820102010080" + "0700c0000102030405060708090a0b0c0d0e0f"
+
+	// SSLv3 ServerHello selecting RC4_128_SHA, without extensions
+	legacySSLv3ServerHello = "160300002a020000260300" +
+		"a1b2c3d4e5f60718293a4b5c6d7e8f900112233445566778899aabbccddeeff0000005" + "00"
+
+	// handshake_failure alert
+	legacyAlert = "15030100020228"
+)
+
+func TestParseSSLv2ServerHelloRaw(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"server hello", legacySSLv2ServerHello, "010080|0002|010080-0700c0|01"},
+		{"no cipher specs", "801f040001000200040000001030820102000102030405060708090a0b0c0d0e0f", "|||"},
+		{"truncated", legacySSLv2ServerHello[:30], "|||"},
+		{"tls alert", legacyAlert, "|||"},
+		{"garbage", "fffb01fffb03", "|||"},
+		{"empty", "", "|||"},
+	}
+	for _, tt := range tests {
+		data, _ := hex.DecodeString(tt.data)
+		if got := ParseSSLv2ServerHello(data); got != tt.want {
+			t.Errorf("%s: ParseSSLv2ServerHello = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRawHashToLegacyHash(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{
+			"sslv2 and sslv3",
+			"010080|0002|010080-0700c0|01,0005|0300||,0005|0300||,0005|0300||,|||,|||",
+			"jl1_01s02a02a02a000000" + "62e8a14bb369ce8834d1490f62eca1fd",
+		},
+		{"no responses", "|||,|||,|||,|||,|||,|||", jarm.LegacyPrefix + strings.Repeat("0", 50)},
+		{"malformed", "0005|0300|,|||", jarm.LegacyPrefix + strings.Repeat("0", 38)},
+	}
+	for _, tt := range tests {
+		if got := RawHashToLegacyHash(tt.raw); got != tt.want {
+			t.Errorf("%s: RawHashToLegacyHash = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+// serveLegacy answers SSLv2 client hellos with an SSLv2 server hello, SSLv2
+// compatible and SSLv3 client hellos with an SSLv3 server hello, and
+// everything else with an alert
+func serveLegacy(l net.Listener) {
+	sslv2, _ := hex.DecodeString(legacySSLv2ServerHello)
+	sslv3, _ := hex.DecodeString(legacySSLv3ServerHello)
+	alert, _ := hex.DecodeString(legacyAlert)
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			header := make([]byte, 11)
+			if _, err := io.ReadFull(conn, header); err != nil {
+				return
+			}
+
+			switch {
+			case header[0]&0x80 != 0 && header[2] == 1 && header[3] == 0x00 && header[4] == 0x02:
+				conn.Write(sslv2)
+			case header[0]&0x80 != 0 && header[2] == 1:
+				conn.Write(sslv3)
+			case header[0] == 22 && header[9] == 3 && header[10] == 0:
+				conn.Write(sslv3)
+			default:
+				conn.Write(alert)
+			}
+		}()
+	}
+}
+
+func TestFingerprintLegacy(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go serveLegacy(l)
+
+	res := Fingerprint(Target{Host: "127.0.0.1", Port: l.Addr().(*net.TCPAddr).Port, Legacy: true})
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+
+	want := []string{"010080|0002|010080-0700c0|01", "0005|0300||", "0005|0300||", "0005|0300||", "|||", "|||"}
+	if len(res.LegacyProbes) != len(want) {
+		t.Fatalf("%d legacy probes, want %d", len(res.LegacyProbes), len(want))
+	}
+	for i, pr := range res.LegacyProbes {
+		if pr.Raw != want[i] {
+			t.Errorf("%s: raw = %q, want %q", pr.Probe.Name, pr.Raw, want[i])
+		}
+	}
+	if res.LegacyHash != "jl1_01s02a02a02a000000"+"62e8a14bb369ce8834d1490f62eca1fd" {
+		t.Errorf("legacy hash = %s", res.LegacyHash)
+	}
+
+	// The standard probes only offer TLS 1.1 and later, so every one of them was refused
+	if res.Hash != ZeroHash {
+		t.Errorf("hash = %s, want the zero hash", res.Hash)
+	}
+}
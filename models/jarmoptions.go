@@ -1,7 +1,14 @@
 package models
 
+// Transports of probes sent as datagrams, probes without one are sent as TLS over TCP
+const (
+	TransportDTLS = "DTLS"
+	TransportQUIC = "QUIC"
+)
+
 // JarmOptions specifies the parameters for a single probe
 type JarmOptions struct {
+	Name           string
 	Hostname       string
 	Port           int
 	Version        int
@@ -11,4 +18,10 @@ type JarmOptions struct {
 	ALPN           string
 	V13Mode        string
 	ExtensionOrder string
+	// Transport is TransportDTLS or TransportQUIC for probes sent as datagrams, empty for TLS over TCP
+	Transport string
+	// Format is "SSLV2" for client hellos in the SSLv2 record format, empty for TLS records
+	Format string
+	// KeyShare is "X25519MLKEM768", "X25519KYBER768" or "HYBRID" for probes offering hybrid post-quantum key shares
+	KeyShare string
 }
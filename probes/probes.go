@@ -2,16 +2,21 @@ package probes
 
 import (
 	"crypto/tls"
+	"strings"
 
 	"github.com/TheGejr/gojarm/ciphers"
+	"github.com/TheGejr/gojarm/dtls"
 	"github.com/TheGejr/gojarm/extension"
+	"github.com/TheGejr/gojarm/handshake"
 	"github.com/TheGejr/gojarm/models"
+	"github.com/TheGejr/gojarm/quic"
 	"github.com/TheGejr/gojarm/utils"
 )
 
 // GetProbes returns the standard set of JARM probes in the correct order
 func GetProbes(hostname string, port int) (jarmProbes []models.JarmOptions) {
 	tls12Forward := models.JarmOptions{
+		Name:           "TLS 1.2 forward",
 		Hostname:       hostname,
 		Port:           port,
 		Version:        tls.VersionTLS12,
@@ -24,6 +29,7 @@ func GetProbes(hostname string, port int) (jarmProbes []models.JarmOptions) {
 	}
 
 	tls12Reverse := models.JarmOptions{
+		Name:           "TLS 1.2 reverse",
 		Hostname:       hostname,
 		Port:           port,
 		Version:        tls.VersionTLS12,
@@ -36,6 +42,7 @@ func GetProbes(hostname string, port int) (jarmProbes []models.JarmOptions) {
 	}
 
 	tls12TopHalf := models.JarmOptions{
+		Name:           "TLS 1.2 top half",
 		Hostname:       hostname,
 		Port:           port,
 		Version:        tls.VersionTLS12,
@@ -48,6 +55,7 @@ func GetProbes(hostname string, port int) (jarmProbes []models.JarmOptions) {
 	}
 
 	tls12BottomHalf := models.JarmOptions{
+		Name:           "TLS 1.2 bottom half",
 		Hostname:       hostname,
 		Port:           port,
 		Version:        tls.VersionTLS12,
@@ -60,6 +68,7 @@ func GetProbes(hostname string, port int) (jarmProbes []models.JarmOptions) {
 	}
 
 	tls12MiddleOut := models.JarmOptions{
+		Name:           "TLS 1.2 middle out",
 		Hostname:       hostname,
 		Port:           port,
 		Version:        tls.VersionTLS12,
@@ -72,6 +81,7 @@ func GetProbes(hostname string, port int) (jarmProbes []models.JarmOptions) {
 	}
 
 	tls11Forward := models.JarmOptions{
+		Name:           "TLS 1.1 forward",
 		Hostname:       hostname,
 		Port:           port,
 		Version:        tls.VersionTLS11,
@@ -84,6 +94,7 @@ func GetProbes(hostname string, port int) (jarmProbes []models.JarmOptions) {
 	}
 
 	tls13Forward := models.JarmOptions{
+		Name:           "TLS 1.3 forward",
 		Hostname:       hostname,
 		Port:           port,
 		Version:        tls.VersionTLS13,
@@ -96,6 +107,7 @@ func GetProbes(hostname string, port int) (jarmProbes []models.JarmOptions) {
 	}
 
 	tls13Reverse := models.JarmOptions{
+		Name:           "TLS 1.3 reverse",
 		Hostname:       hostname,
 		Port:           port,
 		Version:        tls.VersionTLS13,
@@ -108,6 +120,7 @@ func GetProbes(hostname string, port int) (jarmProbes []models.JarmOptions) {
 	}
 
 	tls13Invalid := models.JarmOptions{
+		Name:           "TLS 1.3 invalid",
 		Hostname:       hostname,
 		Port:           port,
 		Version:        tls.VersionTLS13,
@@ -120,6 +133,7 @@ func GetProbes(hostname string, port int) (jarmProbes []models.JarmOptions) {
 	}
 
 	tls13MiddleOut := models.JarmOptions{
+		Name:           "TLS 1.3 middle out",
 		Hostname:       hostname,
 		Port:           port,
 		Version:        tls.VersionTLS13,
@@ -145,7 +159,144 @@ func GetProbes(hostname string, port int) (jarmProbes []models.JarmOptions) {
 	}
 }
 
+// VersionSSL20 is the protocol version of SSLv2 client hellos
+const VersionSSL20 = 0x0002
+
+// GetLegacyProbes returns the legacy probe set, for servers still speaking SSLv2, SSLv3 or TLS 1.0
+//
+// Besides SSLv3 and TLS 1.0 client hellos, it holds a pure SSLv2 client
+// hello and an SSLv2-compatible client hello offering TLS 1.0.
+func GetLegacyProbes(hostname string, port int) []models.JarmOptions {
+	base := models.JarmOptions{
+		Hostname:       hostname,
+		Port:           port,
+		Ciphers:        "NO1.3",
+		CipherOrder:    "FORWARD",
+		Grease:         "NO_GREASE",
+		ALPN:           "NO_SUPPORT",
+		V13Mode:        "NO_SUPPORT",
+		ExtensionOrder: "FORWARD",
+	}
+
+	sslv2 := base
+	sslv2.Name = "SSL 2.0"
+	sslv2.Version = VersionSSL20
+	sslv2.Format = "SSLV2"
+
+	sslv2Compatible := base
+	sslv2Compatible.Name = "SSL 2.0 compatible TLS 1.0"
+	sslv2Compatible.Version = tls.VersionTLS10
+	sslv2Compatible.Format = "SSLV2"
+
+	ssl30Forward := base
+	ssl30Forward.Name = "SSL 3.0 forward"
+	ssl30Forward.Version = tls.VersionSSL30
+
+	ssl30Reverse := base
+	ssl30Reverse.Name = "SSL 3.0 reverse"
+	ssl30Reverse.Version = tls.VersionSSL30
+	ssl30Reverse.CipherOrder = "REVERSE"
+
+	tls10Forward := base
+	tls10Forward.Name = "TLS 1.0 forward"
+	tls10Forward.Version = tls.VersionTLS10
+
+	tls10Reverse := base
+	tls10Reverse.Name = "TLS 1.0 reverse"
+	tls10Reverse.Version = tls.VersionTLS10
+	tls10Reverse.CipherOrder = "REVERSE"
+
+	return []models.JarmOptions{
+		sslv2,
+		sslv2Compatible,
+		ssl30Forward,
+		ssl30Reverse,
+		tls10Forward,
+		tls10Reverse,
+	}
+}
+
+// GetPostQuantumProbes returns probes offering hybrid post-quantum key shares
+//
+// The probes offer X25519MLKEM768, X25519Kyber768Draft00 and both, each
+// along with an X25519 share, so the selected group shows the server's
+// preference.
+func GetPostQuantumProbes(hostname string, port int) []models.JarmOptions {
+	base := models.JarmOptions{
+		Hostname:       hostname,
+		Port:           port,
+		Version:        tls.VersionTLS13,
+		Ciphers:        "ALL",
+		CipherOrder:    "FORWARD",
+		Grease:         "NO_GREASE",
+		ALPN:           "ALPN",
+		V13Mode:        "1.3_SUPPORT",
+		ExtensionOrder: "REVERSE",
+	}
+
+	mlkem := base
+	mlkem.Name = "TLS 1.3 X25519MLKEM768"
+	mlkem.KeyShare = "X25519MLKEM768"
+
+	kyber := base
+	kyber.Name = "TLS 1.3 X25519Kyber768Draft00"
+	kyber.KeyShare = "X25519KYBER768"
+
+	hybrid := base
+	hybrid.Name = "TLS 1.3 hybrid"
+	hybrid.KeyShare = "HYBRID"
+
+	return []models.JarmOptions{
+		mlkem,
+		kyber,
+		hybrid,
+	}
+}
+
+// GetDTLSProbes returns the DTLS probe set, mirroring the standard probes
+//
+// DTLS 1.0 takes the place of TLS 1.1, and the DTLS 1.3 probes offer DTLS
+// 1.3 through the supported_versions extension.
+func GetDTLSProbes(hostname string, port int) (dtlsProbes []models.JarmOptions) {
+	for _, probe := range GetProbes(hostname, port) {
+		probe.Transport = models.TransportDTLS
+		if probe.Version == tls.VersionTLS11 {
+			probe.Name = strings.Replace(probe.Name, "TLS 1.1", "DTLS 1.0", 1)
+		} else {
+			probe.Name = "D" + probe.Name
+		}
+		dtlsProbes = append(dtlsProbes, probe)
+	}
+	return dtlsProbes
+}
+
+// GetQUICProbes returns the QUIC probe set, mirroring the standard probes
+func GetQUICProbes(hostname string, port int) (quicProbes []models.JarmOptions) {
+	for _, probe := range GetProbes(hostname, port) {
+		probe.Transport = models.TransportQUIC
+		probe.Name = "QUIC " + probe.Name
+		quicProbes = append(quicProbes, probe)
+	}
+	return quicProbes
+}
+
+// BuildProbe returns the client hello record for a probe
+//
+// DTLS probes are returned as a single datagram without a cookie, see
+// dtls.WithCookie for answering a HelloVerifyRequest. QUIC probes are
+// returned as a padded Initial packet for a random connection ID.
 func BuildProbe(options models.JarmOptions) (payload []byte) {
+	if options.Format == "SSLV2" {
+		return buildSSLv2Probe(options)
+	}
+
+	switch options.Transport {
+	case models.TransportDTLS:
+		return buildDTLSProbe(options)
+	case models.TransportQUIC:
+		return buildQUICProbe(options)
+	}
+
 	payload = []byte{0x16}
 	hello := []byte{}
 
@@ -172,14 +323,7 @@ func BuildProbe(options models.JarmOptions) (payload []byte) {
 	sessionID := utils.RandomBytes(32)
 	hello = append(hello, byte(len(sessionID)))
 	hello = append(hello, sessionID...)
-
-	cipherChoice := ciphers.GetCiphers(options)
-	hello = append(hello, utils.GetUint16Bytes(len(cipherChoice))...)
-	hello = append(hello, cipherChoice...)
-
-	hello = append(hello, 0x01)
-	hello = append(hello, 0x00)
-	hello = append(hello, extension.GetExtensions(options)...)
+	hello = append(hello, helloTail(options)...)
 
 	innerLen := []byte{0x00}
 	innerLen = append(innerLen, utils.GetUint16Bytes(len(hello))...)
@@ -195,3 +339,102 @@ func BuildProbe(options models.JarmOptions) (payload []byte) {
 
 	return payload
 }
+
+// helloTail returns the cipher suites, compression methods and extensions of a client hello
+func helloTail(options models.JarmOptions) []byte {
+	tail := []byte{}
+
+	cipherChoice := ciphers.GetCiphers(options)
+	tail = append(tail, utils.GetUint16Bytes(len(cipherChoice))...)
+	tail = append(tail, cipherChoice...)
+
+	tail = append(tail, 0x01)
+	tail = append(tail, 0x00)
+	tail = append(tail, extension.GetExtensions(options)...)
+	return tail
+}
+
+// buildDTLSProbe returns the client hello datagram for a DTLS probe
+func buildDTLSProbe(options models.JarmOptions) []byte {
+	record := dtls.Record{Type: 0x16, Version: dtls.VersionDTLS10}
+	hello := []byte{}
+
+	switch options.Version {
+	case tls.VersionTLS11:
+		hello = append(hello, 0xfe, 0xff)
+	case tls.VersionTLS12:
+		record.Version = dtls.VersionDTLS12
+		hello = append(hello, 0xfe, 0xfd)
+	default:
+		hello = append(hello, 0xfe, 0xfd)
+	}
+
+	hello = append(hello, utils.RandomBytes(32)...)
+
+	sessionID := utils.RandomBytes(32)
+	hello = append(hello, byte(len(sessionID)))
+	hello = append(hello, sessionID...)
+
+	// Empty cookie
+	hello = append(hello, 0x00)
+	hello = append(hello, helloTail(options)...)
+
+	record.Fragment = dtls.EncodeHandshake(0x01, 0, hello)
+	return dtls.EncodeRecord(record)
+}
+
+// buildQUICProbe returns the Initial packet for a QUIC probe
+func buildQUICProbe(options models.JarmOptions) []byte {
+	hello := []byte{0x03, 0x03}
+	if options.Version == tls.VersionTLS11 {
+		hello = []byte{0x03, 0x02}
+	}
+	hello = append(hello, utils.RandomBytes(32)...)
+
+	// QUIC does not use the middlebox compatibility session ID
+	hello = append(hello, 0x00)
+	hello = append(hello, helloTail(options)...)
+
+	message := []byte{0x01, 0x00}
+	message = append(message, utils.GetUint16Bytes(len(hello))...)
+	message = append(message, hello...)
+
+	header := quic.Header{Version: quic.Version1, DCID: utils.RandomBytes(8)}
+	client, _ := quic.InitialKeys(header.DCID)
+	packet, err := quic.SealInitial(client, header, 0, quic.PadPayload(header, quic.CryptoFrame(0, message)))
+	if err != nil {
+		return nil
+	}
+	return packet
+}
+
+// buildSSLv2Probe returns an SSLv2 client hello for a probe
+//
+// The SSLv2 cipher kinds are always offered, hellos for later versions also
+// offer the probe's cipher suites as SSLv2 cipher specs.
+func buildSSLv2Probe(options models.JarmOptions) []byte {
+	specs := []byte{}
+	for _, spec := range handshake.SSLv2CipherSpecs {
+		specs = append(specs, byte(spec>>16), byte(spec>>8), byte(spec))
+	}
+	if options.Version != VersionSSL20 {
+		suites := ciphers.GetCiphers(options)
+		for i := 0; i+1 < len(suites); i += 2 {
+			specs = append(specs, 0x00, suites[i], suites[i+1])
+		}
+	}
+
+	challenge := utils.RandomBytes(16)
+
+	hello := []byte{0x01}
+	hello = append(hello, utils.GetUint16Bytes(options.Version)...)
+	hello = append(hello, utils.GetUint16Bytes(len(specs))...)
+	hello = append(hello, 0x00, 0x00)
+	hello = append(hello, utils.GetUint16Bytes(len(challenge))...)
+	hello = append(hello, specs...)
+	hello = append(hello, challenge...)
+
+	payload := utils.GetUint16Bytes(len(hello))
+	payload[0] |= 0x80
+	return append(payload, hello...)
+}
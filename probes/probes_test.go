@@ -0,0 +1,62 @@
+package probes
+
+import (
+	"crypto/tls"
+	"encoding/binary"
+	"testing"
+
+	"github.com/TheGejr/gojarm/handshake"
+)
+
+func TestBuildSSLv2Probe(t *testing.T) {
+	count := 0
+	for _, probe := range GetLegacyProbes("example.com", 443) {
+		if probe.Format != "SSLV2" {
+			continue
+		}
+		count++
+		data := BuildProbe(probe)
+
+		// Two byte record header with the high bit set
+		if len(data) < 11 || data[0]&0x80 == 0 {
+			t.Fatalf("%s: not an SSLv2 record: %x", probe.Name, data)
+		}
+		if length := int(binary.BigEndian.Uint16(data[0:2]) & 0x7fff); length != len(data)-2 {
+			t.Errorf("%s: record length %d, want %d", probe.Name, length, len(data)-2)
+		}
+
+		hello := data[2:]
+		version := binary.BigEndian.Uint16(hello[1:3])
+		specsLen := int(binary.BigEndian.Uint16(hello[3:5]))
+		sessionLen := int(binary.BigEndian.Uint16(hello[5:7]))
+		challengeLen := int(binary.BigEndian.Uint16(hello[7:9]))
+		if hello[0] != 1 || int(version) != probe.Version || sessionLen != 0 || challengeLen != 16 {
+			t.Errorf("%s: type %d, version %04x, session ID %d, challenge %d", probe.Name, hello[0], version, sessionLen, challengeLen)
+		}
+		if specsLen%3 != 0 || 9+specsLen+challengeLen != len(hello) {
+			t.Errorf("%s: cipher specs length %d in a %d byte hello", probe.Name, specsLen, len(hello))
+			continue
+		}
+
+		// The SSLv2 cipher kinds come first, followed by the TLS suites of compatible hellos
+		specs := hello[9 : 9+specsLen]
+		for i, kind := range handshake.SSLv2CipherSpecs {
+			if got := uint32(specs[3*i])<<16 | uint32(specs[3*i+1])<<8 | uint32(specs[3*i+2]); got != kind {
+				t.Errorf("%s: cipher spec %d = %06x, want %06x", probe.Name, i, got, kind)
+			}
+		}
+		tlsSpecs := specs[3*len(handshake.SSLv2CipherSpecs):]
+		if (len(tlsSpecs) > 0) != (probe.Version == tls.VersionTLS10) {
+			t.Errorf("%s: %d TLS cipher suites offered", probe.Name, len(tlsSpecs)/3)
+		}
+		for i := 0; i < len(tlsSpecs); i += 3 {
+			if tlsSpecs[i] != 0 {
+				t.Errorf("%s: TLS cipher suite %x is not a 00xxxx spec", probe.Name, tlsSpecs[i:i+3])
+			}
+		}
+	}
+
+	if count != 2 {
+		t.Errorf("%d SSLv2 probes, want 2", count)
+	}
+}
@@ -0,0 +1,156 @@
+package protocols
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+)
+
+// Protocols that can be identified from the first bytes of a response
+const (
+	Unknown    = "unknown"
+	TLS        = "tls"
+	DTLS       = "dtls"
+	QUIC       = "quic"
+	SSLv2      = "sslv2"
+	HTTP       = "http"
+	SSH        = "ssh"
+	SMTP       = "smtp"
+	FTP        = "ftp"
+	POP3       = "pop3"
+	IMAP       = "imap"
+	Redis      = "redis"
+	MySQL      = "mysql"
+	PostgreSQL = "postgresql"
+	RDP        = "rdp"
+	VNC        = "vnc"
+	XMPP       = "xmpp"
+	Telnet     = "telnet"
+	Memcached  = "memcached"
+	AMQP       = "amqp"
+	Rsync      = "rsync"
+)
+
+// IsTLSRecord reports whether a response starts with a TLS record header
+func IsTLSRecord(data []byte) bool {
+	if len(data) < 3 {
+		return false
+	}
+	return data[0] >= 20 && data[0] <= 23 && data[1] == 3
+}
+
+// IsDTLSRecord reports whether a response starts with a DTLS record header
+func IsDTLSRecord(data []byte) bool {
+	if len(data) < 13 {
+		return false
+	}
+	return data[0] >= 20 && data[0] <= 25 && data[1] == 0xfe
+}
+
+// IsSSLv2 reports whether a response starts with an SSLv2 server hello or error
+//
+// Server hellos have to offer version 2 and list lengths that add up to the
+// record length, errors have to carry one of the defined error codes. The
+// rest of a server hello, usually a long certificate, may be cut off.
+func IsSSLv2(data []byte) bool {
+	// Server hellos and errors are never padded, so only two byte headers are accepted
+	if len(data) < 5 || data[0]&0x80 == 0 {
+		return false
+	}
+	length := int(data[0]&0x7f)<<8 | int(data[1])
+	msg := data[2:]
+
+	switch msg[0] {
+	case 4:
+		// session ID hit, certificate type, version and the certificate, cipher specs and connection ID lengths
+		if len(msg) < 11 || msg[3] != 0x00 || msg[4] != 0x02 {
+			return false
+		}
+		certLen := int(binary.BigEndian.Uint16(msg[5:7]))
+		specsLen := int(binary.BigEndian.Uint16(msg[7:9]))
+		connLen := int(binary.BigEndian.Uint16(msg[9:11]))
+		return specsLen > 0 && specsLen%3 == 0 && connLen >= 16 && connLen <= 32 && 11+certLen+specsLen+connLen == length
+	case 0:
+		// NO-CIPHER, NO-CERTIFICATE, BAD-CERTIFICATE or UNSUPPORTED-CERTIFICATE-TYPE
+		code := binary.BigEndian.Uint16(msg[1:3])
+		return length == 3 && (code == 1 || code == 2 || code == 4 || code == 6)
+	}
+	return false
+}
+
+// Identify returns the protocol a response was sent by, based on its first bytes
+func Identify(data []byte) string {
+	if len(data) == 0 {
+		return Unknown
+	}
+
+	if IsTLSRecord(data) {
+		return TLS
+	}
+	if IsDTLSRecord(data) {
+		return DTLS
+	}
+	if IsSSLv2(data) {
+		return SSLv2
+	}
+
+	text := string(data)
+	upper := strings.ToUpper(text)
+
+	switch {
+	case strings.HasPrefix(text, "SSH-"):
+		return SSH
+	case strings.HasPrefix(text, "HTTP/"),
+		strings.HasPrefix(upper, "<!DOCTYPE HTML"),
+		strings.HasPrefix(upper, "<HTML"):
+		return HTTP
+	case strings.HasPrefix(text, "220"):
+		if strings.Contains(upper, "FTP") {
+			return FTP
+		}
+		return SMTP
+	case strings.HasPrefix(text, "+OK"):
+		return POP3
+	case strings.HasPrefix(text, "* OK"), strings.HasPrefix(text, "* PREAUTH"), strings.HasPrefix(text, "* BYE"):
+		return IMAP
+	case strings.HasPrefix(text, "-ERR"), strings.HasPrefix(text, "-NOAUTH"), strings.HasPrefix(text, "-DENIED"):
+		return Redis
+	case strings.HasPrefix(text, "RFB "):
+		return VNC
+	case strings.HasPrefix(text, "<?xml"), strings.HasPrefix(text, "<stream:"):
+		return XMPP
+	case strings.HasPrefix(text, "AMQP"):
+		return AMQP
+	case strings.HasPrefix(text, "@RSYNCD"):
+		return Rsync
+	case strings.HasPrefix(text, "ERROR\r\n"), strings.HasPrefix(text, "CLIENT_ERROR"):
+		return Memcached
+	}
+
+	// Binary protocols
+	switch {
+	case isMySQL(data):
+		return MySQL
+	case data[0] == 'E' && len(data) > 5 && bytes.Contains(data, []byte("SFATAL")):
+		return PostgreSQL
+	case len(data) >= 4 && data[0] == 0x03 && data[1] == 0x00:
+		return RDP
+	case data[0] == 0xff && len(data) > 1 && data[1] >= 0xfb:
+		return Telnet
+	}
+
+	return Unknown
+}
+
+// isMySQL reports whether a response is a MySQL greeting or error packet
+func isMySQL(data []byte) bool {
+	if len(data) < 5 || data[3] != 0 {
+		return false
+	}
+	length := int(data[0]) | int(data[1])<<8 | int(data[2])<<16
+	if length == 0 || length > 0xffff {
+		return false
+	}
+	// protocol version 10 greeting, or an error packet
+	return data[4] == 0x0a || data[4] == 0xff
+}
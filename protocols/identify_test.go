@@ -0,0 +1,111 @@
+package protocols
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+const (
+	// SSLv2 SERVER-HELLO with a 4 byte certificate, one cipher spec and a 16 byte connection ID
+	sslv2ServerHello = "8022 04 00 01 0002 0004 0003 0010 30820102 010080 000102030405060708090a0b0c0d0e0f"
+
+	// SSLv2 SERVER-HELLO cut off inside a 1024 byte certificate
+	sslv2TruncatedServerHello = "841e 04 00 01 0002 0400 0003 0010 3082 03fc 3082 02e4"
+)
+
+func TestIdentify(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"tls server hello", "160303003b020000370303", TLS},
+		{"tls alert", "15030100020228", TLS},
+		{"dtls hello verify request", "16feff000000000000000000230300", DTLS},
+		{"sslv2 server hello", sslv2ServerHello, SSLv2},
+		{"truncated sslv2 server hello", sslv2TruncatedServerHello, SSLv2},
+		{"sslv2 error", "8003 00 0001", SSLv2},
+		{"telnet will echo", "fffb01fffb03fffd18", Telnet},
+		{"telnet iac", "fffb00", Telnet},
+		{"mysql greeting", hex.EncodeToString([]byte("\x4a\x00\x00\x00\x0a8.0.36\x00")), MySQL},
+		{"mysql error", hex.EncodeToString([]byte("\x17\x00\x00\x00\xff\x6a\x04Host blocked")), MySQL},
+		{"postgresql error", hex.EncodeToString([]byte("E\x00\x00\x00\x58SFATAL\x00")), PostgreSQL},
+		{"rdp", "0300000b06d00000123400", RDP},
+		{"empty", "", Unknown},
+		{"random bytes", "9c1e5a77", Unknown},
+	}
+
+	for _, tt := range tests {
+		data, err := hex.DecodeString(strings.ReplaceAll(tt.data, " ", ""))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := Identify(data); got != tt.want {
+			t.Errorf("%s: Identify = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIdentifyBanners(t *testing.T) {
+	tests := []struct {
+		banner string
+		want   string
+	}{
+		{"SSH-2.0-OpenSSH_9.6\r\n", SSH},
+		{"HTTP/1.1 400 Bad Request\r\n", HTTP},
+		{"<!DOCTYPE html>", HTTP},
+		{"220 mail.example.com ESMTP Postfix\r\n", SMTP},
+		{"220 ProFTPD Server ready\r\n", FTP},
+		{"+OK POP3 ready\r\n", POP3},
+		{"* OK [CAPABILITY IMAP4rev1] ready\r\n", IMAP},
+		{"-ERR unknown command\r\n", Redis},
+		{"-NOAUTH Authentication required.\r\n", Redis},
+		{"RFB 003.008\n", VNC},
+		{"<?xml version='1.0'?><stream:stream>", XMPP},
+		{"AMQP\x00\x00\x09\x01", AMQP},
+		{"@RSYNCD: 31.0\n", Rsync},
+		{"ERROR\r\n", Memcached},
+		{"hello\r\n", Unknown},
+	}
+
+	for _, tt := range tests {
+		if got := Identify([]byte(tt.banner)); got != tt.want {
+			t.Errorf("Identify(%q) = %s, want %s", tt.banner, got, tt.want)
+		}
+	}
+}
+
+func TestIsSSLv2(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want bool
+	}{
+		{"server hello", sslv2ServerHello, true},
+		{"truncated certificate", sslv2TruncatedServerHello, true},
+		{"error", "8003 00 0001", true},
+		{"telnet iac", "fffb00", false},
+		{"telnet negotiation", "fffb01fffb03fffd18", false},
+		{"three byte header", "0022 04 04 00 01 0002 0004 0003 0010", false},
+		{"client hello", "802e 01 0002 0015 0000 0010", false},
+		{"version 3", "8022 04 00 01 0300 0004 0003 0010 30820102 010080 000102030405060708090a0b0c0d0e0f", false},
+		{"length mismatch", "8030 04 00 01 0002 0004 0003 0010 30820102 010080 000102030405060708090a0b0c0d0e0f", false},
+		{"partial cipher spec", "8021 04 00 01 0002 0004 0002 0010 30820102 0100 000102030405060708090a0b0c0d0e0f", false},
+		{"short connection id", "8016 04 00 01 0002 0004 0003 0004 30820102 010080 00010203", false},
+		{"truncated header", "8022 04 00 01 0002", false},
+		{"unknown error code", "8003 00 0003", false},
+		{"long error", "8004 00 0001 00", false},
+		{"empty", "", false},
+	}
+
+	for _, tt := range tests {
+		data, err := hex.DecodeString(strings.ReplaceAll(tt.data, " ", ""))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := IsSSLv2(data); got != tt.want {
+			t.Errorf("%s: IsSSLv2 = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package gojarm
+
+import (
+	"errors"
+
+	"github.com/TheGejr/gojarm/models"
+	"github.com/TheGejr/gojarm/probes"
+	"github.com/TheGejr/gojarm/protocols"
+	"github.com/TheGejr/gojarm/quic"
+)
+
+// TransportQUIC selects QUIC Initial packets over UDP for a target
+const TransportQUIC = models.TransportQUIC
+
+// probeQUIC sends a single QUIC probe to the target and parses the response
+//
+// The server hello is taken from the CRYPTO frames of the server's Initial
+// packets. A Retry packet is answered by resending the Initial packet with
+// the retry token, and the reply to that is used as the response of the probe.
+func probeQUIC(t Target, probe models.JarmOptions) (ProbeResult, error) {
+	pr := ProbeResult{Probe: probe}
+
+	conn, err := dialTarget(t)
+	if err != nil {
+		return pr, err
+	}
+	defer conn.Close()
+
+	data := probes.BuildProbe(probe)
+	header, _, err := quic.ParseHeader(data)
+	if err != nil {
+		return pr, nil
+	}
+
+	reply := exchangeDatagram(conn, data)
+	if retry, _, err := quic.ParseHeader(reply); err == nil && retry.Version != 0 && retry.Type == quic.PacketRetry {
+		if data, err = quic.WithRetry(data, retry); err != nil {
+			return pr, nil
+		}
+		header.DCID = retry.SCID
+		reply = exchangeDatagram(conn, data)
+	}
+
+	pr.Response = reply
+	if len(reply) > 0 {
+		pr.Protocol = protocols.QUIC
+	}
+
+	// Coalesced Handshake packets can not be decrypted with the Initial keys and are skipped
+	_, server := quic.InitialKeys(header.DCID)
+	frames := quic.Frames{}
+	for packet := reply; len(packet) > 0; {
+		_, payload, rest, err := quic.OpenInitial(server, packet)
+		if err == nil {
+			err = frames.ParseFrames(payload)
+		}
+		if err != nil && !errors.Is(err, quic.ErrNotInitial) {
+			break
+		}
+		packet = rest
+	}
+
+	fingerprintResponse(&pr, t, frames.ToTLS(), nil, 'q', nil)
+
+	return pr, nil
+}
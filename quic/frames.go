@@ -0,0 +1,198 @@
+package quic
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Frame types handled in Initial packets
+const (
+	framePadding          = 0x00
+	framePing             = 0x01
+	frameAck              = 0x02
+	frameAckECN           = 0x03
+	frameCrypto           = 0x06
+	frameConnectionClose  = 0x1c
+	frameApplicationClose = 0x1d
+)
+
+// cryptoErrorBase is added to TLS alert codes to form QUIC CRYPTO_ERROR codes
+const cryptoErrorBase = 0x0100
+
+// ConnectionClose is a CONNECTION_CLOSE frame received from the server
+type ConnectionClose struct {
+	ErrorCode uint64
+	Reason    string
+}
+
+func (c *ConnectionClose) Error() string {
+	return fmt.Sprintf("quic: connection closed: %#x %s", c.ErrorCode, c.Reason)
+}
+
+// Alert returns the TLS alert carried by a CRYPTO_ERROR close, if any
+func (c *ConnectionClose) Alert() (byte, bool) {
+	if c.ErrorCode < cryptoErrorBase || c.ErrorCode > cryptoErrorBase+0xff {
+		return 0, false
+	}
+	return byte(c.ErrorCode - cryptoErrorBase), true
+}
+
+// Frames holds the frames of decrypted Initial packets that carry information
+type Frames struct {
+	// Crypto holds CRYPTO frame data by offset
+	Crypto map[uint64][]byte
+	Close  *ConnectionClose
+}
+
+// CryptoFrame returns a CRYPTO frame
+func CryptoFrame(offset uint64, data []byte) []byte {
+	out := []byte{frameCrypto}
+	out = AppendVarint(out, offset)
+	out = AppendVarint(out, uint64(len(data)))
+	return append(out, data...)
+}
+
+// ParseFrames adds the frames of a decrypted Initial packet payload
+//
+// Only the frames allowed in Initial packets are accepted.
+func (f *Frames) ParseFrames(payload []byte) error {
+	if f.Crypto == nil {
+		f.Crypto = map[uint64][]byte{}
+	}
+
+	for len(payload) > 0 {
+		frameType, n := ReadVarint(payload)
+		if n == 0 {
+			return errors.New("quic: truncated frame")
+		}
+		payload = payload[n:]
+
+		switch frameType {
+		case framePadding, framePing:
+		case frameAck, frameAckECN:
+			// largest acknowledged, delay, range count and first range
+			fields := []uint64{}
+			for i := 0; i < 4; i++ {
+				v, n := ReadVarint(payload)
+				if n == 0 {
+					return errors.New("quic: truncated ACK frame")
+				}
+				fields = append(fields, v)
+				payload = payload[n:]
+			}
+			// gap and length of every further range, and the ECN counts
+			count := fields[2] * 2
+			if frameType == frameAckECN {
+				count += 3
+			}
+			for i := uint64(0); i < count; i++ {
+				_, n := ReadVarint(payload)
+				if n == 0 {
+					return errors.New("quic: truncated ACK frame")
+				}
+				payload = payload[n:]
+			}
+		case frameCrypto:
+			offset, n := ReadVarint(payload)
+			if n == 0 {
+				return errors.New("quic: truncated CRYPTO frame")
+			}
+			payload = payload[n:]
+			length, n := ReadVarint(payload)
+			if n == 0 || uint64(len(payload)-n) < length {
+				return errors.New("quic: truncated CRYPTO frame")
+			}
+			payload = payload[n:]
+			f.Crypto[offset] = payload[:length]
+			payload = payload[length:]
+		case frameConnectionClose, frameApplicationClose:
+			code, n := ReadVarint(payload)
+			if n == 0 {
+				return errors.New("quic: truncated CONNECTION_CLOSE frame")
+			}
+			payload = payload[n:]
+			if frameType == frameConnectionClose {
+				// the type of the frame that caused the error
+				if _, n = ReadVarint(payload); n == 0 {
+					return errors.New("quic: truncated CONNECTION_CLOSE frame")
+				}
+				payload = payload[n:]
+			}
+			length, n := ReadVarint(payload)
+			if n == 0 || uint64(len(payload)-n) < length {
+				return errors.New("quic: truncated CONNECTION_CLOSE frame")
+			}
+			payload = payload[n:]
+			f.Close = &ConnectionClose{ErrorCode: code, Reason: string(payload[:length])}
+			payload = payload[length:]
+		default:
+			return fmt.Errorf("quic: unexpected frame type in Initial packet: %#x", frameType)
+		}
+	}
+	return nil
+}
+
+// CryptoStream returns the contiguous CRYPTO data from offset zero
+func (f *Frames) CryptoStream() []byte {
+	stream := []byte{}
+	for {
+		data, ok := f.Crypto[uint64(len(stream))]
+		if !ok || len(data) == 0 {
+			return stream
+		}
+		stream = append(stream, data...)
+	}
+}
+
+// AppendVarint appends a QUIC variable-length integer
+func AppendVarint(b []byte, v uint64) []byte {
+	switch {
+	case v < 1<<6:
+		return append(b, byte(v))
+	case v < 1<<14:
+		return append(b, byte(v>>8)|0x40, byte(v))
+	case v < 1<<30:
+		return append(b, byte(v>>24)|0x80, byte(v>>16), byte(v>>8), byte(v))
+	}
+	return append(b, byte(v>>56)|0xc0, byte(v>>48), byte(v>>40), byte(v>>32), byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+// ReadVarint reads a QUIC variable-length integer, returning zero bytes read if it is truncated
+func ReadVarint(b []byte) (uint64, int) {
+	if len(b) == 0 {
+		return 0, 0
+	}
+	n := 1 << (b[0] >> 6)
+	if len(b) < n {
+		return 0, 0
+	}
+	v := uint64(b[0] & 0x3f)
+	for _, c := range b[1:n] {
+		v = v<<8 | uint64(c)
+	}
+	return v, n
+}
+
+// ToTLS returns a TLS view of the server hello or the alert carried by the frames
+//
+// The server hello is wrapped in a TLS 1.2 handshake record, so the TLS
+// parsers apply, and a CRYPTO_ERROR close is converted to a fatal alert
+// record. Nil is returned if the frames hold neither.
+func (f *Frames) ToTLS() []byte {
+	stream := f.CryptoStream()
+	if len(stream) >= 4 && stream[0] == 2 {
+		length := int(stream[1])<<16 | int(stream[2])<<8 | int(stream[3])
+		if len(stream) >= 4+length {
+			message := stream[:4+length]
+			out := []byte{22, 0x03, 0x03, byte(len(message) >> 8), byte(len(message))}
+			return append(out, message...)
+		}
+	}
+
+	if f.Close != nil {
+		if alert, ok := f.Close.Alert(); ok {
+			return []byte{21, 0x03, 0x03, 0x00, 0x02, 0x02, alert}
+		}
+	}
+	return nil
+}
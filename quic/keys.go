@@ -0,0 +1,70 @@
+package quic
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+)
+
+// Version1 is QUIC version 1, RFC 9000
+const Version1 = 0x00000001
+
+// initialSaltV1 is the salt Initial secrets are derived with for QUIC version 1
+var initialSaltV1 = []byte{
+	0x38, 0x76, 0x2c, 0xf7, 0xf5, 0x59, 0x34, 0xb3, 0x4d, 0x17,
+	0x9a, 0xe6, 0xa4, 0xc8, 0x0c, 0xad, 0xcc, 0xbb, 0x7f, 0x0a,
+}
+
+// Keys protect the packets sent in one direction
+type Keys struct {
+	Key []byte
+	IV  []byte
+	HP  []byte
+}
+
+// InitialKeys returns the Initial packet protection keys for a destination connection ID
+//
+// The client keys protect packets sent by the client, the server keys those
+// sent by the server. Both are derived from the connection ID the client
+// chose for its first Initial packet.
+func InitialKeys(dcid []byte) (client Keys, server Keys) {
+	initial := hkdfExtract(initialSaltV1, dcid)
+	client = packetKeys(hkdfExpandLabel(initial, "client in", sha256.Size))
+	server = packetKeys(hkdfExpandLabel(initial, "server in", sha256.Size))
+	return client, server
+}
+
+// packetKeys derives the AES-128-GCM key, IV and header protection key from a secret
+func packetKeys(secret []byte) Keys {
+	return Keys{
+		Key: hkdfExpandLabel(secret, "quic key", 16),
+		IV:  hkdfExpandLabel(secret, "quic iv", 12),
+		HP:  hkdfExpandLabel(secret, "quic hp", 16),
+	}
+}
+
+// hkdfExtract is HKDF-Extract with SHA-256, RFC 5869
+func hkdfExtract(salt, secret []byte) []byte {
+	mac := hmac.New(sha256.New, salt)
+	mac.Write(secret)
+	return mac.Sum(nil)
+}
+
+// hkdfExpandLabel is HKDF-Expand-Label of TLS 1.3 with SHA-256 and an empty context, RFC 8446
+func hkdfExpandLabel(secret []byte, label string, length int) []byte {
+	full := "tls13 " + label
+	info := []byte{byte(length >> 8), byte(length), byte(len(full))}
+	info = append(info, full...)
+	info = append(info, 0x00)
+
+	out := []byte{}
+	prev := []byte{}
+	for i := byte(1); len(out) < length; i++ {
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(prev)
+		mac.Write(info)
+		mac.Write([]byte{i})
+		prev = mac.Sum(nil)
+		out = append(out, prev...)
+	}
+	return out[:length]
+}
@@ -0,0 +1,117 @@
+package quic
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+// Initial secrets and keys of RFC 9001, Appendix A.1
+func TestInitialKeys(t *testing.T) {
+	dcid, _ := hex.DecodeString("8394c8f03e515708")
+
+	initial := hkdfExtract(initialSaltV1, dcid)
+	secrets := []struct {
+		name   string
+		secret []byte
+		want   string
+	}{
+		{"initial", initial, "7db5df06e7a69e432496adedb00851923595221596ae2ae9fb8115c1e9ed0a44"},
+		{"client in", hkdfExpandLabel(initial, "client in", 32), "c00cf151ca5be075ed0ebfb5c80323c42d6b7db67881289af4008f1f6c357aea"},
+		{"server in", hkdfExpandLabel(initial, "server in", 32), "3c199828fd139efd216c155ad844cc81fb82fa8d7446fa7d78be803acdda951b"},
+	}
+	for _, tt := range secrets {
+		if got := hex.EncodeToString(tt.secret); got != tt.want {
+			t.Errorf("%s secret = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	client, server := InitialKeys(dcid)
+	keys := []struct {
+		name string
+		got  []byte
+		want string
+	}{
+		{"client key", client.Key, "1f369613dd76d5467730efcbe3b1a22d"},
+		{"client iv", client.IV, "fa044b2f42a3fd3b46fb255c"},
+		{"client hp", client.HP, "9f50449e04a0e810283a1e9933adedd2"},
+		{"server key", server.Key, "cf3a5331653c364c88f0f379b6067e37"},
+		{"server iv", server.IV, "0ac1493ca1905853b0bba03e"},
+		{"server hp", server.HP, "c206b8d9b9f0f37644430b490eeaa314"},
+	}
+	for _, tt := range keys {
+		if got := hex.EncodeToString(tt.got); got != tt.want {
+			t.Errorf("%s = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+// Protected server Initial of RFC 9001, Appendix A.3
+func TestOpenInitial(t *testing.T) {
+	dcid, _ := hex.DecodeString("8394c8f03e515708")
+	packet, _ := hex.DecodeString("cf000000010008f067a5502a4262b5004075c0d95a482cd0991cd25b0aac406a" +
+		"5816b6394100f37a1c69797554780bb38cc5a99f5ede4cf73c3ec2493a1839b3" +
+		"dbcba3f6ea46c5b7684df3548e7ddeb9c3bf9c73cc3f3bded74b562bfb19fb84" +
+		"022f8ef4cdd93795d77d06edbb7aaf2f58891850abbdca3d20398c276456cbc4" +
+		"2158407dd074ee")
+	want, _ := hex.DecodeString("02000000000600405a020000560303eefce7f7b37ba1d1632e96677825ddf739" +
+		"88cfc79825df566dc5430b9a045a1200130100002e00330024001d00209d3c94" +
+		"0d89690b84d08a60993c144eca684d1081287c834d5311bcf32bb9da1a002b00" +
+		"020304")
+
+	client, server := InitialKeys(dcid)
+	h, payload, rest, err := OpenInitial(server, packet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Type != PacketInitial || h.Version != Version1 || len(h.DCID) != 0 || hex.EncodeToString(h.SCID) != "f067a5502a4262b5" || len(rest) != 0 {
+		t.Errorf("header = %+v, rest = %x", h, rest)
+	}
+	if !bytes.Equal(payload, want) {
+		t.Errorf("payload = %x", payload)
+	}
+
+	frames := Frames{}
+	if err := frames.ParseFrames(payload); err != nil {
+		t.Fatal(err)
+	}
+	if stream := frames.CryptoStream(); !bytes.Equal(stream, want[9:]) {
+		t.Errorf("crypto stream = %x", stream)
+	}
+
+	// The client keys do not open packets of the server
+	if _, _, _, err := OpenInitial(client, packet); err == nil {
+		t.Errorf("server Initial opened with the client keys")
+	}
+}
+
+func TestSealInitial(t *testing.T) {
+	dcid, _ := hex.DecodeString("8394c8f03e515708")
+	client, _ := InitialKeys(dcid)
+	h := Header{Type: PacketInitial, Version: Version1, DCID: dcid, SCID: []byte{1, 2, 3, 4}, Token: []byte("token")}
+	payload := PadPayload(h, CryptoFrame(0, []byte{0x01, 0x00, 0x00, 0x00}))
+
+	packet, err := SealInitial(client, h, 7, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(packet) != MinInitialSize {
+		t.Errorf("sealed Initial is %d bytes, want %d", len(packet), MinInitialSize)
+	}
+
+	// A coalesced packet following the Initial is returned as the rest
+	coalesced := append(append([]byte{}, packet...), 0xe0, 0x00)
+	got, opened, rest, err := OpenInitial(client, coalesced)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(opened, payload) || !bytes.Equal(got.Token, h.Token) || !bytes.Equal(got.SCID, h.SCID) || !bytes.Equal(rest, []byte{0xe0, 0x00}) {
+		t.Errorf("OpenInitial = %+v, %x, rest %x", got, opened, rest)
+	}
+
+	corrupted := append([]byte{}, packet...)
+	corrupted[len(corrupted)-1] ^= 0xff
+	if _, _, _, err := OpenInitial(client, corrupted); err == nil {
+		t.Errorf("corrupted Initial opened")
+	}
+}
@@ -0,0 +1,253 @@
+package quic
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+)
+
+// Long header packet types
+const (
+	PacketInitial   = 0x0
+	Packet0RTT      = 0x1
+	PacketHandshake = 0x2
+	PacketRetry     = 0x3
+)
+
+// MinInitialSize is the size client Initial datagrams are padded to
+const MinInitialSize = 1200
+
+// ErrNotInitial is returned by OpenInitial for packets that are not Initial packets
+var ErrNotInitial = errors.New("quic: not an Initial packet")
+
+// Header is a long packet header
+type Header struct {
+	Type    byte
+	Version uint32
+	DCID    []byte
+	SCID    []byte
+	// Token is the token of Initial packets, or the retry token of Retry packets
+	Token []byte
+}
+
+// longHeader is a parsed long header and the location of the rest of the packet
+type longHeader struct {
+	Header
+	pnOffset int
+	end      int
+}
+
+// parseLongHeader parses the long header at the start of a datagram
+func parseLongHeader(data []byte) (longHeader, error) {
+	h := longHeader{}
+	if len(data) < 7 || data[0]&0x80 == 0 {
+		return h, errors.New("quic: not a long header packet")
+	}
+	h.Type = (data[0] >> 4) & 0x3
+	h.Version = binary.BigEndian.Uint32(data[1:5])
+
+	offset := 5
+	dcidLen := int(data[offset])
+	if len(data) < offset+1+dcidLen+1 {
+		return h, errors.New("quic: truncated header")
+	}
+	h.DCID = data[offset+1 : offset+1+dcidLen]
+	offset += 1 + dcidLen
+	scidLen := int(data[offset])
+	if len(data) < offset+1+scidLen {
+		return h, errors.New("quic: truncated header")
+	}
+	h.SCID = data[offset+1 : offset+1+scidLen]
+	offset += 1 + scidLen
+
+	// Version negotiation packets list versions instead of a payload
+	if h.Version == 0 {
+		h.pnOffset = offset
+		h.end = len(data)
+		return h, nil
+	}
+
+	switch h.Type {
+	case PacketRetry:
+		// The retry token is followed by a 16 byte integrity tag
+		if len(data) < offset+16 {
+			return h, errors.New("quic: truncated Retry packet")
+		}
+		h.Token = data[offset : len(data)-16]
+		h.pnOffset = len(data)
+		h.end = len(data)
+		return h, nil
+	case PacketInitial:
+		tokenLen, n := ReadVarint(data[offset:])
+		if n == 0 || uint64(len(data)-offset-n) < tokenLen {
+			return h, errors.New("quic: truncated token")
+		}
+		h.Token = data[offset+n : offset+n+int(tokenLen)]
+		offset += n + int(tokenLen)
+	}
+
+	length, n := ReadVarint(data[offset:])
+	if n == 0 || uint64(len(data)-offset-n) < length {
+		return h, errors.New("quic: truncated packet")
+	}
+	h.pnOffset = offset + n
+	h.end = h.pnOffset + int(length)
+	return h, nil
+}
+
+// ParseHeader parses the long header at the start of a datagram
+//
+// The rest of the datagram, holding any coalesced packets, is returned along with it.
+func ParseHeader(data []byte) (Header, []byte, error) {
+	h, err := parseLongHeader(data)
+	if err != nil {
+		return Header{}, nil, err
+	}
+	return h.Header, data[h.end:], nil
+}
+
+// SealInitial returns a protected Initial packet
+//
+// The packet number is always encoded in four bytes.
+func SealInitial(keys Keys, h Header, pn uint32, payload []byte) ([]byte, error) {
+	aead, block, err := newCiphers(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	const pnLen = 4
+	packet := []byte{0xc0 | PacketInitial<<4 | (pnLen - 1)}
+	packet = binary.BigEndian.AppendUint32(packet, h.Version)
+	packet = append(packet, byte(len(h.DCID)))
+	packet = append(packet, h.DCID...)
+	packet = append(packet, byte(len(h.SCID)))
+	packet = append(packet, h.SCID...)
+	packet = AppendVarint(packet, uint64(len(h.Token)))
+	packet = append(packet, h.Token...)
+
+	// The length is always encoded in two bytes, so it is known before sealing
+	length := pnLen + len(payload) + aead.Overhead()
+	if length >= 1<<14 {
+		return nil, errors.New("quic: payload too large")
+	}
+	packet = append(packet, byte(length>>8)|0x40, byte(length))
+	pnOffset := len(packet)
+	packet = binary.BigEndian.AppendUint32(packet, pn)
+
+	packet = aead.Seal(packet, nonce(keys.IV, uint64(pn)), payload, packet)
+
+	// Header protection samples the ciphertext four bytes after the packet number offset
+	mask := make([]byte, aes.BlockSize)
+	block.Encrypt(mask, packet[pnOffset+4:pnOffset+4+aes.BlockSize])
+	packet[0] ^= mask[0] & 0x0f
+	for i := 0; i < pnLen; i++ {
+		packet[pnOffset+i] ^= mask[1+i]
+	}
+	return packet, nil
+}
+
+// OpenInitial removes the protection of the Initial packet at the start of a datagram
+//
+// The header, the decrypted payload and the rest of the datagram are
+// returned. Packets of other types result in ErrNotInitial, along with the
+// rest of the datagram so coalesced packets can be skipped.
+func OpenInitial(keys Keys, data []byte) (Header, []byte, []byte, error) {
+	h, err := parseLongHeader(data)
+	if err != nil {
+		return Header{}, nil, nil, err
+	}
+	rest := data[h.end:]
+	if h.Version == 0 || h.Type != PacketInitial {
+		return h.Header, nil, rest, ErrNotInitial
+	}
+	if h.end < h.pnOffset+4+aes.BlockSize {
+		return h.Header, nil, rest, errors.New("quic: packet too short for header protection")
+	}
+
+	aead, block, err := newCiphers(keys)
+	if err != nil {
+		return h.Header, nil, rest, err
+	}
+
+	packet := append([]byte{}, data[:h.end]...)
+	mask := make([]byte, aes.BlockSize)
+	block.Encrypt(mask, packet[h.pnOffset+4:h.pnOffset+4+aes.BlockSize])
+	packet[0] ^= mask[0] & 0x0f
+	pnLen := int(packet[0]&0x3) + 1
+
+	pn := uint64(0)
+	for i := 0; i < pnLen; i++ {
+		packet[h.pnOffset+i] ^= mask[1+i]
+		pn = pn<<8 | uint64(packet[h.pnOffset+i])
+	}
+
+	headerEnd := h.pnOffset + pnLen
+	payload, err := aead.Open(nil, nonce(keys.IV, pn), packet[headerEnd:], packet[:headerEnd])
+	if err != nil {
+		return h.Header, nil, rest, errors.New("quic: Initial packet failed to decrypt")
+	}
+	return h.Header, payload, rest, nil
+}
+
+// newCiphers returns the AEAD and header protection ciphers of the keys
+func newCiphers(keys Keys) (cipher.AEAD, cipher.Block, error) {
+	block, err := aes.NewCipher(keys.Key)
+	if err != nil {
+		return nil, nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, nil, err
+	}
+	hp, err := aes.NewCipher(keys.HP)
+	if err != nil {
+		return nil, nil, err
+	}
+	return aead, hp, nil
+}
+
+// nonce returns the IV combined with a packet number
+func nonce(iv []byte, pn uint64) []byte {
+	n := append([]byte{}, iv...)
+	for i := 0; i < 8; i++ {
+		n[len(n)-1-i] ^= byte(pn >> (8 * i))
+	}
+	return n
+}
+
+// PadPayload pads an Initial packet payload with PADDING frames, so the sealed packet is MinInitialSize bytes
+func PadPayload(h Header, payload []byte) []byte {
+	// first byte, version, connection IDs, token, two byte length, four byte packet number and the AEAD tag
+	size := 7 + len(h.DCID) + len(h.SCID) + len(AppendVarint(nil, uint64(len(h.Token)))) + len(h.Token) + 2 + 4 + 16
+	if size+len(payload) >= MinInitialSize {
+		return payload
+	}
+	return append(payload, make([]byte, MinInitialSize-size-len(payload))...)
+}
+
+// WithRetry returns a client Initial packet resent after a Retry packet
+//
+// The payload of the original packet is sealed again for the connection ID
+// chosen by the server, carrying the retry token.
+func WithRetry(initial []byte, retry Header) ([]byte, error) {
+	h, _, err := ParseHeader(initial)
+	if err != nil {
+		return nil, err
+	}
+	client, _ := InitialKeys(h.DCID)
+	_, payload, _, err := OpenInitial(client, initial)
+	if err != nil {
+		return nil, err
+	}
+
+	frames := Frames{}
+	if err := frames.ParseFrames(payload); err != nil {
+		return nil, err
+	}
+
+	h.DCID = retry.SCID
+	h.Token = retry.Token
+	client, _ = InitialKeys(h.DCID)
+	return SealInitial(client, h, 1, PadPayload(h, CryptoFrame(0, frames.CryptoStream())))
+}
@@ -0,0 +1,143 @@
+package gojarm
+
+import (
+	"bytes"
+	"encoding/hex"
+	"net"
+	"testing"
+
+	"github.com/TheGejr/gojarm/jarm"
+	"github.com/TheGejr/gojarm/quic"
+)
+
+const (
+	// quicServerHello is the ServerHello of RFC 9001, Appendix A.3
+	quicServerHello = "020000560303eefce7f7b37ba1d1632e96677825ddf73988cfc79825df566dc5" +
+		"430b9a045a1200130100002e00330024001d00209d3c940d89690b84d08a6099" +
+		"3c144eca684d1081287c834d5311bcf32bb9da1a002b00020304"
+
+	// quicShortServerHello only carries supported_versions, so its TLS record is under 85 bytes
+	quicShortServerHello = "0200002e0303eefce7f7b37ba1d1632e96677825ddf73988cfc79825df566dc5" +
+		"430b9a045a12001301000006002b00020304"
+)
+
+// quicServer is a stand-in QUIC server answering client Initials with a ServerHello
+type quicServer struct {
+	t     *testing.T
+	conn  net.PacketConn
+	retry bool
+	// serverHello is the hex encoded ServerHello message sent in the CRYPTO frame
+	serverHello string
+
+	// initials counts the client Initials that carried a client hello
+	initials int
+}
+
+// serve opens client Initials with the server's view of the client keys and answers them
+func (s *quicServer) serve() {
+	sh, _ := hex.DecodeString(s.serverHello)
+	token := []byte("retry token")
+	retryID := []byte{0xa0, 0xa1, 0xa2, 0xa3, 0xa4, 0xa5, 0xa6, 0xa7}
+
+	buff := make([]byte, 65535)
+	for {
+		n, addr, err := s.conn.ReadFrom(buff)
+		if err != nil {
+			return
+		}
+		if n < quic.MinInitialSize {
+			s.t.Errorf("client Initial datagram is %d bytes, want at least %d", n, quic.MinInitialSize)
+		}
+
+		header, _, err := quic.ParseHeader(buff[:n])
+		if err != nil {
+			s.t.Errorf("client Initial: %s", err)
+			continue
+		}
+		client, server := quic.InitialKeys(header.DCID)
+		h, payload, _, err := quic.OpenInitial(client, buff[:n])
+		if err != nil {
+			s.t.Errorf("client Initial: %s", err)
+			continue
+		}
+		frames := quic.Frames{}
+		if err := frames.ParseFrames(payload); err != nil || len(frames.CryptoStream()) == 0 || frames.CryptoStream()[0] != 1 {
+			s.t.Errorf("client Initial does not carry a client hello: %x", payload)
+			continue
+		}
+
+		if s.retry && !bytes.Equal(h.Token, token) {
+			if len(h.Token) != 0 {
+				s.t.Errorf("unexpected token %x", h.Token)
+			}
+			packet := []byte{0xf0, 0x00, 0x00, 0x00, 0x01, byte(len(h.SCID))}
+			packet = append(packet, h.SCID...)
+			packet = append(packet, byte(len(retryID)))
+			packet = append(packet, retryID...)
+			packet = append(packet, token...)
+			s.conn.WriteTo(append(packet, make([]byte, 16)...), addr)
+			continue
+		}
+		if s.retry && !bytes.Equal(h.DCID, retryID) {
+			s.t.Errorf("retried Initial sent to %x, want %x", h.DCID, retryID)
+		}
+
+		s.initials++
+		reply, err := quic.SealInitial(server, quic.Header{
+			Type:    quic.PacketInitial,
+			Version: quic.Version1,
+			DCID:    h.SCID,
+			SCID:    []byte{0xf0, 0x67, 0xa5, 0x50, 0x2a, 0x42, 0x62, 0xb5},
+		}, 0, quic.CryptoFrame(0, sh))
+		if err != nil {
+			s.t.Error(err)
+			continue
+		}
+		s.conn.WriteTo(reply, addr)
+	}
+}
+
+func TestFingerprintQUIC(t *testing.T) {
+	tests := []struct {
+		name        string
+		retry       bool
+		serverHello string
+		raw         string
+	}{
+		{"server hello", false, quicServerHello, "1301|0303||0033-002b"},
+		{"retry", true, quicServerHello, "1301|0303||0033-002b"},
+		{"short server hello", false, quicShortServerHello, "1301|0303||002b"},
+	}
+
+	for _, tt := range tests {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := &quicServer{t: t, conn: conn, retry: tt.retry, serverHello: tt.serverHello}
+		done := make(chan int)
+		go func() {
+			s.serve()
+			close(done)
+		}()
+
+		res := Fingerprint(Target{Host: "127.0.0.1", Port: conn.LocalAddr().(*net.UDPAddr).Port, Transport: TransportQUIC})
+		conn.Close()
+		<-done
+		if res.Error != nil {
+			t.Fatal(res.Error)
+		}
+
+		if s.initials != len(res.Probes) || len(res.Probes) == 0 {
+			t.Errorf("%s: %d client Initials answered, want %d", tt.name, s.initials, len(res.Probes))
+		}
+		for _, pr := range res.Probes {
+			if pr.Raw != tt.raw {
+				t.Errorf("%s: %s: raw = %q, want %q", tt.name, pr.Probe.Name, pr.Raw, tt.raw)
+			}
+		}
+		if prefix, _ := jarm.SplitPrefix(res.Hash); prefix != jarm.QUICPrefix || res.Hash == jarm.QUICPrefix+ZeroHash {
+			t.Errorf("%s: hash = %s", tt.name, res.Hash)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package gojarm
+
+import (
+	"net"
+	"time"
+
+	"github.com/TheGejr/gojarm/extension"
+	"github.com/TheGejr/gojarm/handshake"
+	"github.com/TheGejr/gojarm/models"
+	"github.com/TheGejr/gojarm/protocols"
+)
+
+// setKeyShare records the key share group of a server hello, flagging HelloRetryRequests
+func setKeyShare(pr *ProbeResult, sh *handshake.ServerHello) {
+	group, ok := sh.KeyShareGroup()
+	if sh.IsHelloRetryRequest() {
+		pr.HelloRetryRequest = true
+		if ok {
+			pr.RequestedGroup = handshake.GroupName(group)
+		}
+		return
+	}
+	if ok {
+		pr.Group = handshake.GroupName(group)
+	}
+}
+
+// followRetry answers a HelloRetryRequest with a second client hello on the same
+// connection, returning the outcome or nil if the second client hello could not be built
+func followRetry(t Target, conn net.Conn, probe models.JarmOptions, hello []byte, hrr *handshake.ServerHello) *ProbeResult {
+	group, ok := hrr.KeyShareGroup()
+	if !ok {
+		return nil
+	}
+	share, ok := extension.KeyShareForGroup(group)
+	if !ok {
+		return nil
+	}
+	cookie, _ := hrr.Extension(handshake.ExtCookie)
+	data, err := handshake.RetryClientHello(hello, group, share, cookie)
+	if err != nil {
+		return nil
+	}
+
+	pr := &ProbeResult{Probe: probe}
+	conn.SetWriteDeadline(time.Now().Add(time.Second * 5))
+	if _, err := conn.Write(data); err != nil {
+		return pr
+	}
+
+	// The change_cipher_spec sent after the HelloRetryRequest may arrive on its own
+	conn.SetReadDeadline(time.Now().Add(time.Second * 5))
+	buff := make([]byte, 1484)
+	n := 0
+	for n == 0 {
+		read, err := conn.Read(buff)
+		n = copy(buff, handshake.SkipChangeCipherSpec(buff[:read]))
+		if err != nil {
+			break
+		}
+	}
+
+	pr.Response = buff[:n]
+	if n > 0 {
+		pr.Protocol = protocols.Identify(pr.Response)
+	}
+	fingerprintResponse(pr, t, pr.Response, handshake.ClientHelloSessionID(data), 't', nil)
+
+	return pr
+}
@@ -0,0 +1,156 @@
+package gojarm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+
+	"github.com/TheGejr/gojarm/handshake"
+)
+
+const (
+	// HelloRetryRequest asking for a secp256r1 share with a cookie, followed by a change_cipher_spec
+	retryHelloRetryRequest = "160303004202" + "00003e0303" +
+		"cf21ad74e59a6111be1d8c021e65b891c2a211167abb8c5e079e09e2c8a8339c001301000016" +
+		"002b00020304003300020017002c00060004deadbeef" +
+		"140303000101"
+	// ServerHello without a key share, for client hellos that offer none
+	retryServerHello = "16030300320200002e0303" +
+		"eefce7f7b37ba1d1632e96677825ddf73988cfc79825df566dc5430b9a045a12001301000004" +
+		"002b00020304"
+	// ServerHello selecting a secp256r1 share, answering the second client hello
+	retryServerHelloP256 = "160303003c020000380303" +
+		"eefce7f7b37ba1d1632e96677825ddf73988cfc79825df566dc5430b9a045a12001301000010" +
+		"002b00020304003300060017000200ff"
+)
+
+// clientHelloExtensions returns the extensions of a client hello record
+func clientHelloExtensions(record []byte) map[uint16][]byte {
+	exts := map[uint16][]byte{}
+	body := record[9:]
+	offset := 35 + int(body[34])
+	offset += 2 + int(binary.BigEndian.Uint16(body[offset:]))
+	offset += 1 + int(body[offset])
+	if len(body) < offset+2 {
+		return exts
+	}
+	for ext := offset + 2; ext+4 <= len(body); {
+		l := int(binary.BigEndian.Uint16(body[ext+2:]))
+		exts[binary.BigEndian.Uint16(body[ext:])] = body[ext+4 : ext+4+l]
+		ext += 4 + l
+	}
+	return exts
+}
+
+// readRecord reads a single TLS record
+func readRecord(conn net.Conn) ([]byte, error) {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return nil, err
+	}
+	record := make([]byte, 5+int(binary.BigEndian.Uint16(header[3:])))
+	copy(record, header)
+	_, err := io.ReadFull(conn, record[5:])
+	return record, err
+}
+
+// serveRetry answers client hellos offering a key share with a HelloRetryRequest, and all others with a ServerHello
+func serveRetry(t *testing.T, l net.Listener, retries *int32) {
+	hrr, _ := hex.DecodeString(retryHelloRetryRequest)
+	sh, _ := hex.DecodeString(retryServerHello)
+	shP256, _ := hex.DecodeString(retryServerHelloP256)
+	cookie, _ := hex.DecodeString("0004deadbeef")
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			hello, err := readRecord(conn)
+			if err != nil {
+				return
+			}
+			if _, ok := clientHelloExtensions(hello)[handshake.ExtKeyShare]; !ok {
+				conn.Write(sh)
+				return
+			}
+			conn.Write(hrr)
+
+			// Without FollowRetry the client closes the connection here
+			retry, err := readRecord(conn)
+			if err != nil {
+				return
+			}
+			exts := clientHelloExtensions(retry)
+			share := exts[handshake.ExtKeyShare]
+			if len(share) != 2+4+65 || binary.BigEndian.Uint16(share[2:]) != 0x0017 || binary.BigEndian.Uint16(share[4:]) != 65 {
+				t.Errorf("second client hello key share = %x", share)
+			}
+			if !bytes.Equal(exts[handshake.ExtCookie], cookie) {
+				t.Errorf("second client hello cookie = %x", exts[handshake.ExtCookie])
+			}
+			atomic.AddInt32(retries, 1)
+			conn.Write(shP256)
+		}()
+	}
+}
+
+func TestFingerprintFollowRetry(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	var retries int32
+	go serveRetry(t, l, &retries)
+
+	for _, follow := range []bool{false, true} {
+		atomic.StoreInt32(&retries, 0)
+		res := Fingerprint(Target{Host: "127.0.0.1", Port: l.Addr().(*net.TCPAddr).Port, FollowRetry: follow})
+		if res.Error != nil {
+			t.Fatal(res.Error)
+		}
+
+		requests := 0
+		for _, pr := range res.Probes {
+			if !pr.HelloRetryRequest {
+				if pr.Group != "" || pr.Retry != nil {
+					t.Errorf("follow %v: %s: group %q, retry %v without a HelloRetryRequest", follow, pr.Probe.Name, pr.Group, pr.Retry)
+				}
+				continue
+			}
+			requests++
+			if pr.Raw != "1301|0303||002b-0033-002c" {
+				t.Errorf("follow %v: %s: raw = %q", follow, pr.Probe.Name, pr.Raw)
+			}
+			if pr.RequestedGroup != "secp256r1" || pr.Group != "" {
+				t.Errorf("follow %v: %s: requested group %q, group %q", follow, pr.Probe.Name, pr.RequestedGroup, pr.Group)
+			}
+			if (pr.Retry != nil) != follow {
+				t.Errorf("follow %v: %s: retry = %v", follow, pr.Probe.Name, pr.Retry)
+				continue
+			}
+			if follow && (pr.Retry.HelloRetryRequest || pr.Retry.Group != "secp256r1" || pr.Retry.Raw != "1301|0303||002b-0033") {
+				t.Errorf("follow %v: %s: retry = %+v", follow, pr.Probe.Name, pr.Retry)
+			}
+		}
+
+		if requests == 0 {
+			t.Errorf("follow %v: no HelloRetryRequests", follow)
+		}
+		want := 0
+		if follow {
+			want = requests
+		}
+		if n := int(atomic.LoadInt32(&retries)); n != want {
+			t.Errorf("follow %v: %d second client hellos, want %d", follow, n, want)
+		}
+	}
+}
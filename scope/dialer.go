@@ -0,0 +1,64 @@
+package scope
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+
+	"golang.org/x/net/proxy"
+)
+
+// dialer enforces a policy before handing connections to the forward dialer
+type dialer struct {
+	policy  *Policy
+	forward proxy.Dialer
+}
+
+// Dialer returns a dialer that only connects to targets in scope
+//
+// Hostnames are resolved before connecting and every resolved address is
+// checked against the policy. The connection is made to the first address
+// in scope, so the forward dialer never resolves the name itself.
+func (p *Policy) Dialer(forward proxy.Dialer) proxy.Dialer {
+	return &dialer{policy: p, forward: forward}
+}
+
+// Dial connects to the address if it is in scope
+func (d *dialer) Dial(network, addr string) (net.Conn, error) {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port: %s", portStr)
+	}
+
+	if err := d.policy.CheckPort(port); err != nil {
+		return nil, err
+	}
+
+	addrs := []netip.Addr{}
+	if ip, err := netip.ParseAddr(host); err == nil {
+		addrs = append(addrs, ip)
+	} else {
+		addrs, err = net.DefaultResolver.LookupNetIP(context.Background(), "ip", host)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Refuse the whole name if any address is out of scope, so round-robin DNS can not sneak past the policy
+	for _, ip := range addrs {
+		if err := d.policy.CheckAddr(host, ip); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no addresses found for %s", host)
+	}
+	return d.forward.Dial(network, net.JoinHostPort(addrs[0].Unmap().String(), portStr))
+}
@@ -0,0 +1,55 @@
+package scope
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+// recordingDialer records the addresses it is asked to dial
+type recordingDialer struct {
+	dialed []string
+}
+
+func (d *recordingDialer) Dial(network, addr string) (net.Conn, error) {
+	d.dialed = append(d.dialed, addr)
+	return nil, errors.New("not connecting in tests")
+}
+
+func TestDialer(t *testing.T) {
+	p := &Policy{}
+	if err := p.Allow("203.0.113.0/24"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Deny("port:22"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		addr   string
+		dialed string
+	}{
+		{"203.0.113.5:443", "203.0.113.5:443"},
+		{"[::ffff:203.0.113.5]:443", "203.0.113.5:443"},
+		{"203.0.113.5:22", ""},
+		{"192.0.2.1:443", ""},
+		{"localhost:443", ""},
+	}
+
+	for _, tt := range tests {
+		forward := &recordingDialer{}
+		_, err := p.Dialer(forward).Dial("tcp", tt.addr)
+		if tt.dialed == "" {
+			if !errors.Is(err, ErrOutOfScope) {
+				t.Errorf("Dial(%s) = %v, want ErrOutOfScope", tt.addr, err)
+			}
+			if len(forward.dialed) > 0 {
+				t.Errorf("Dial(%s) reached the forward dialer: %v", tt.addr, forward.dialed)
+			}
+			continue
+		}
+		if len(forward.dialed) != 1 || forward.dialed[0] != tt.dialed {
+			t.Errorf("Dial(%s) dialed %v, want %s", tt.addr, forward.dialed, tt.dialed)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package scope
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/TheGejr/gojarm/utils"
+)
+
+// ErrOutOfScope is returned for hosts, addresses and ports the policy does not allow
+var ErrOutOfScope = errors.New("target out of scope")
+
+// reservedPrefixes are refused when DenyReserved is set
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("10.0.0.0/8"),
+	netip.MustParsePrefix("127.0.0.0/8"),
+	netip.MustParsePrefix("169.254.0.0/16"),
+	netip.MustParsePrefix("172.16.0.0/12"),
+	netip.MustParsePrefix("192.168.0.0/16"),
+	netip.MustParsePrefix("100.100.100.200/32"),
+	netip.MustParsePrefix("::/128"),
+	netip.MustParsePrefix("::1/128"),
+	netip.MustParsePrefix("fc00::/7"),
+	netip.MustParsePrefix("fe80::/10"),
+}
+
+// Policy decides which hosts, addresses and ports may be contacted
+//
+// A target is in scope when it matches an allow rule, or no allow rules are
+// given, and matches no deny rule. Host rules are glob patterns matched
+// against the hostname, CIDR rules are matched against every address the
+// hostname resolves to.
+type Policy struct {
+	AllowCIDRs []netip.Prefix
+	DenyCIDRs  []netip.Prefix
+	AllowHosts []string
+	DenyHosts  []string
+	AllowPorts []PortRange
+	DenyPorts  []PortRange
+
+	// DenyReserved refuses RFC1918, loopback, link-local and cloud metadata addresses
+	DenyReserved bool
+}
+
+// PortRange is an inclusive range of ports, a single port has Start equal to End
+type PortRange struct {
+	Start int
+	End   int
+}
+
+// Contains reports whether the port is in the range
+func (r PortRange) Contains(port int) bool {
+	return port >= r.Start && port <= r.End
+}
+
+// Allow adds an allow rule
+//
+// A rule is an address or CIDR, a port or port range written as "port:443"
+// or "port:8000-8100", or a hostname glob such as "*.example.com".
+func (p *Policy) Allow(rule string) error {
+	return p.addRule(rule, true)
+}
+
+// Deny adds a deny rule, written like the rules of Allow
+func (p *Policy) Deny(rule string) error {
+	return p.addRule(rule, false)
+}
+
+// addRule parses a rule and adds it to the allow or deny rules
+func (p *Policy) addRule(rule string, allow bool) error {
+	rule = strings.TrimSpace(rule)
+	if rule == "" {
+		return errors.New("invalid scope rule: empty")
+	}
+
+	if strings.HasPrefix(rule, "port:") {
+		ports := strings.TrimPrefix(rule, "port:")
+		from, to, isRange := strings.Cut(ports, "-")
+		start, err := strconv.Atoi(from)
+		if err != nil || !utils.ValidPort(start) {
+			return fmt.Errorf("invalid scope port: %s", rule)
+		}
+		end := start
+		if isRange {
+			if end, err = strconv.Atoi(to); err != nil || !utils.ValidPort(end) || end < start {
+				return fmt.Errorf("invalid scope port range: %s", rule)
+			}
+		}
+		if allow {
+			p.AllowPorts = append(p.AllowPorts, PortRange{Start: start, End: end})
+		} else {
+			p.DenyPorts = append(p.DenyPorts, PortRange{Start: start, End: end})
+		}
+		return nil
+	}
+
+	prefix, err := netip.ParsePrefix(rule)
+	if err != nil {
+		if addr, aerr := netip.ParseAddr(rule); aerr == nil {
+			prefix, err = addr.Prefix(addr.BitLen())
+		}
+	}
+	if err == nil {
+		if allow {
+			p.AllowCIDRs = append(p.AllowCIDRs, prefix.Masked())
+		} else {
+			p.DenyCIDRs = append(p.DenyCIDRs, prefix.Masked())
+		}
+		return nil
+	}
+
+	if _, err := path.Match(rule, ""); err != nil {
+		return fmt.Errorf("invalid scope host pattern: %s", rule)
+	}
+	if allow {
+		p.AllowHosts = append(p.AllowHosts, strings.ToLower(rule))
+	} else {
+		p.DenyHosts = append(p.DenyHosts, strings.ToLower(rule))
+	}
+	return nil
+}
+
+// CheckPort returns ErrOutOfScope if the port may not be contacted
+func (p *Policy) CheckPort(port int) error {
+	if containsPort(p.DenyPorts, port) {
+		return fmt.Errorf("%w: port %d is denied", ErrOutOfScope, port)
+	}
+	if len(p.AllowPorts) > 0 && !containsPort(p.AllowPorts, port) {
+		return fmt.Errorf("%w: port %d is not allowed", ErrOutOfScope, port)
+	}
+	return nil
+}
+
+// CheckAddr returns ErrOutOfScope if the address, resolved from host, may not be contacted
+//
+// The host is the name the address was resolved from, or the address itself.
+func (p *Policy) CheckAddr(host string, addr netip.Addr) error {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	addr = addr.Unmap()
+
+	if matchHost(p.DenyHosts, host) {
+		return fmt.Errorf("%w: host %s is denied", ErrOutOfScope, host)
+	}
+	if matchAddr(p.DenyCIDRs, addr) {
+		return fmt.Errorf("%w: address %s is denied", ErrOutOfScope, addr)
+	}
+	if p.DenyReserved && matchAddr(reservedPrefixes, addr) {
+		return fmt.Errorf("%w: address %s is reserved", ErrOutOfScope, addr)
+	}
+
+	if len(p.AllowHosts) == 0 && len(p.AllowCIDRs) == 0 {
+		return nil
+	}
+	if matchHost(p.AllowHosts, host) || matchAddr(p.AllowCIDRs, addr) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s (%s) is not allowed", ErrOutOfScope, host, addr)
+}
+
+func containsPort(ranges []PortRange, port int) bool {
+	for _, r := range ranges {
+		if r.Contains(port) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchHost(patterns []string, host string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, host); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
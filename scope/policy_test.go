@@ -0,0 +1,135 @@
+package scope
+
+import (
+	"errors"
+	"net/netip"
+	"testing"
+)
+
+func TestPortRules(t *testing.T) {
+	for _, rule := range []string{
+		"port:",
+		"port:http",
+		"port:0",
+		"port:65536",
+		"port:0-100000000",
+		"port:1-100000000",
+		"port:100-10",
+		"port:80-",
+	} {
+		p := &Policy{}
+		if err := p.Allow(rule); err == nil {
+			t.Errorf("Allow(%s) accepted an invalid port rule: %+v", rule, p.AllowPorts)
+		}
+	}
+
+	p := &Policy{}
+	for _, rule := range []string{"port:443", "port:8000-8100", "port:1-65535"} {
+		if err := p.Deny(rule); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []PortRange{{443, 443}, {8000, 8100}, {1, 65535}}
+	if len(p.DenyPorts) != len(want) {
+		t.Fatalf("DenyPorts = %+v, want ranges %+v", p.DenyPorts, want)
+	}
+	for i := range want {
+		if p.DenyPorts[i] != want[i] {
+			t.Errorf("DenyPorts[%d] = %+v, want %+v", i, p.DenyPorts[i], want[i])
+		}
+	}
+
+	p = &Policy{}
+	if err := p.Allow("port:443"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Allow("port:8000-8100"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Deny("port:8080"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		port int
+		ok   bool
+	}{
+		{443, true},
+		{444, false},
+		{7999, false},
+		{8000, true},
+		{8080, false},
+		{8100, true},
+		{8101, false},
+	}
+	for _, tt := range tests {
+		err := p.CheckPort(tt.port)
+		if (err == nil) != tt.ok {
+			t.Errorf("CheckPort(%d) = %v", tt.port, err)
+		}
+		if err != nil && !errors.Is(err, ErrOutOfScope) {
+			t.Errorf("CheckPort(%d) does not wrap ErrOutOfScope: %v", tt.port, err)
+		}
+	}
+}
+
+func TestCheckAddr(t *testing.T) {
+	p := &Policy{DenyReserved: true}
+	for _, rule := range []string{"203.0.113.0/24", "2001:db8::/32", "198.51.100.7", "*.example.com"} {
+		if err := p.Allow(rule); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, rule := range []string{"203.0.113.128/25", "admin.example.com"} {
+		if err := p.Deny(rule); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		host string
+		addr string
+		ok   bool
+	}{
+		{"203.0.113.1", "203.0.113.1", true},
+		{"203.0.113.200", "203.0.113.200", false},
+		{"198.51.100.7", "198.51.100.7", true},
+		{"198.51.100.8", "198.51.100.8", false},
+		{"2001:db8::1", "2001:db8::1", true},
+		{"::ffff:203.0.113.1", "::ffff:203.0.113.1", true},
+		{"www.example.com", "192.0.2.1", true},
+		{"WWW.Example.COM.", "192.0.2.1", true},
+		{"example.com", "192.0.2.1", false},
+		{"admin.example.com", "203.0.113.1", false},
+		{"www.example.org", "192.0.2.1", false},
+		// Reserved addresses are refused even if allowed by name
+		{"www.example.com", "10.0.0.1", false},
+		{"www.example.com", "169.254.169.254", false},
+		{"www.example.com", "::1", false},
+		{"www.example.com", "::ffff:127.0.0.1", false},
+		{"www.example.com", "fd00::1", false},
+	}
+
+	for _, tt := range tests {
+		err := p.CheckAddr(tt.host, netip.MustParseAddr(tt.addr))
+		if (err == nil) != tt.ok {
+			t.Errorf("CheckAddr(%s, %s) = %v", tt.host, tt.addr, err)
+		}
+		if err != nil && !errors.Is(err, ErrOutOfScope) {
+			t.Errorf("CheckAddr(%s, %s) does not wrap ErrOutOfScope: %v", tt.host, tt.addr, err)
+		}
+	}
+
+	// Without allow rules everything not denied is in scope
+	open := &Policy{}
+	if err := open.CheckAddr("anything.example", netip.MustParseAddr("10.0.0.1")); err != nil {
+		t.Errorf("empty policy refused an address: %s", err)
+	}
+
+	if err := p.Allow("[a-"); err == nil {
+		t.Error("Allow accepted an invalid host pattern")
+	}
+	if err := p.Allow(" "); err == nil {
+		t.Error("Allow accepted an empty rule")
+	}
+}
@@ -0,0 +1,103 @@
+package starttls
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+// BER tags used by the LDAP negotiation
+const (
+	berInteger     = 0x02
+	berOctetString = 0x04
+	berEnumerated  = 0x0a
+	berSequence    = 0x30
+)
+
+// berElement is a decoded BER element
+type berElement struct {
+	tag     byte
+	content []byte
+}
+
+// berEncode encodes a single BER element with a definite length
+func berEncode(tag byte, content []byte) []byte {
+	out := []byte{tag}
+	switch n := len(content); {
+	case n < 0x80:
+		out = append(out, byte(n))
+	case n <= 0xff:
+		out = append(out, 0x81, byte(n))
+	default:
+		out = append(out, 0x82, byte(n>>8), byte(n))
+	}
+	return append(out, content...)
+}
+
+// berInt encodes a small non-negative integer
+func berInt(tag byte, v int) []byte {
+	content := []byte{byte(v)}
+	for v >>= 8; v > 0; v >>= 8 {
+		content = append([]byte{byte(v)}, content...)
+	}
+	if content[0]&0x80 != 0 {
+		content = append([]byte{0}, content...)
+	}
+	return berEncode(tag, content)
+}
+
+// berRead reads a single BER element with a definite length
+func berRead(r io.Reader) (berElement, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return berElement{}, err
+	}
+
+	length := int(header[1])
+	if length&0x80 != 0 {
+		n := length & 0x7f
+		if n == 0 || n > 3 {
+			return berElement{}, errors.New("ber: unsupported length")
+		}
+		octets := make([]byte, n)
+		if _, err := io.ReadFull(r, octets); err != nil {
+			return berElement{}, err
+		}
+		length = 0
+		for _, b := range octets {
+			length = length<<8 | int(b)
+		}
+	}
+
+	content := make([]byte, length)
+	if _, err := io.ReadFull(r, content); err != nil {
+		return berElement{}, err
+	}
+	return berElement{tag: header[0], content: content}, nil
+}
+
+// berChildren decodes the elements of a constructed element
+func berChildren(data []byte) ([]berElement, error) {
+	children := []berElement{}
+	r := bytes.NewReader(data)
+	for r.Len() > 0 {
+		e, err := berRead(r)
+		if err != nil {
+			return nil, errors.New("ber: truncated element")
+		}
+		children = append(children, e)
+	}
+	return children, nil
+}
+
+// berToInt decodes the content of an integer or enumerated element
+func berToInt(content []byte) (int, error) {
+	if len(content) == 0 || len(content) > 4 {
+		return 0, errors.New("ber: invalid integer")
+	}
+	v := int(int8(content[0]))
+	for _, b := range content[1:] {
+		v = v<<8 | int(b)
+	}
+	return v, nil
+}
@@ -0,0 +1,245 @@
+package starttls
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// PostgreSQL upgrades PostgreSQL connections using an SSLRequest
+type PostgreSQL struct{}
+
+// Negotiate implements Negotiator
+func (PostgreSQL) Negotiate(conn net.Conn, hostname string) (net.Conn, error) {
+	conn.SetDeadline(time.Now().Add(Timeout))
+
+	// Length followed by the SSLRequest code 80877103
+	if _, err := conn.Write([]byte{0x00, 0x00, 0x00, 0x08, 0x04, 0xd2, 0x16, 0x2f}); err != nil {
+		return nil, err
+	}
+
+	reply := make([]byte, 1)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return nil, err
+	}
+
+	switch reply[0] {
+	case 'S':
+		conn.SetDeadline(time.Time{})
+		return conn, nil
+	case 'N':
+		return nil, refused("postgresql", "SSL not supported")
+	}
+	return nil, fmt.Errorf("postgresql: unexpected reply: %q", reply[0])
+}
+
+// MySQL capability flags
+const (
+	mysqlClientLongPassword     = 0x00000001
+	mysqlClientLongFlag         = 0x00000004
+	mysqlClientProtocol41       = 0x00000200
+	mysqlClientSSL              = 0x00000800
+	mysqlClientTransactions     = 0x00002000
+	mysqlClientSecureConnection = 0x00008000
+	mysqlClientPluginAuth       = 0x00080000
+)
+
+// MySQL upgrades MySQL connections by answering the server greeting with an SSL request
+type MySQL struct{}
+
+// Negotiate implements Negotiator
+func (MySQL) Negotiate(conn net.Conn, hostname string) (net.Conn, error) {
+	conn.SetDeadline(time.Now().Add(Timeout))
+
+	seq, greeting, err := readMySQLPacket(conn)
+	if err != nil {
+		return nil, err
+	}
+	if len(greeting) > 0 && greeting[0] == 0xff {
+		msg := ""
+		if len(greeting) > 3 {
+			msg = string(greeting[3:])
+		}
+		return nil, refused("mysql", msg)
+	}
+	if len(greeting) == 0 || greeting[0] != 0x0a {
+		return nil, errors.New("mysql: unexpected greeting")
+	}
+
+	// Protocol version, null terminated server version, thread id, auth data and a filler before the capabilities
+	offset := 1
+	for offset < len(greeting) && greeting[offset] != 0 {
+		offset++
+	}
+	offset += 1 + 4 + 8 + 1
+	if len(greeting) < offset+2 {
+		return nil, errors.New("mysql: greeting too short")
+	}
+	if binary.LittleEndian.Uint16(greeting[offset:offset+2])&mysqlClientSSL == 0 {
+		return nil, refused("mysql", "SSL not supported")
+	}
+
+	request := make([]byte, 4+32)
+	request[0] = 32
+	request[3] = seq + 1
+	binary.LittleEndian.PutUint32(request[4:8], mysqlClientLongPassword|mysqlClientLongFlag|mysqlClientProtocol41|
+		mysqlClientSSL|mysqlClientTransactions|mysqlClientSecureConnection|mysqlClientPluginAuth)
+	binary.LittleEndian.PutUint32(request[8:12], 1<<24)
+	request[12] = 0x21 // utf8_general_ci
+
+	if _, err := conn.Write(request); err != nil {
+		return nil, err
+	}
+
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+// readMySQLPacket reads a single MySQL packet and returns its sequence number and payload
+func readMySQLPacket(conn net.Conn) (byte, []byte, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return 0, nil, err
+	}
+	length := int(header[0]) | int(header[1])<<8 | int(header[2])<<16
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(conn, payload); err != nil {
+		return 0, nil, err
+	}
+	return header[3], payload, nil
+}
+
+// TDS packet types and pre-login options
+const (
+	tdsPacketReply    = 0x04
+	tdsPacketPrelogin = 0x12
+	tdsStatusEOM      = 0x01
+
+	tdsOptionVersion    = 0x00
+	tdsOptionEncryption = 0x01
+	tdsOptionTerminator = 0xff
+
+	tdsEncryptOn     = 0x01
+	tdsEncryptNotSup = 0x02
+)
+
+// MSSQL upgrades Microsoft SQL Server connections using a TDS pre-login
+//
+// The TLS handshake of TDS is carried inside pre-login packets, so the
+// returned connection wraps the probe and unwraps the server hello.
+type MSSQL struct{}
+
+// Negotiate implements Negotiator
+func (MSSQL) Negotiate(conn net.Conn, hostname string) (net.Conn, error) {
+	conn.SetDeadline(time.Now().Add(Timeout))
+
+	// VERSION and ENCRYPTION options, followed by their data
+	prelogin := []byte{
+		tdsOptionVersion, 0x00, 0x0b, 0x00, 0x06,
+		tdsOptionEncryption, 0x00, 0x11, 0x00, 0x01,
+		tdsOptionTerminator,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		tdsEncryptOn,
+	}
+
+	tds := &tdsConn{Conn: conn, packetType: tdsPacketPrelogin}
+	if _, err := tds.Write(prelogin); err != nil {
+		return nil, err
+	}
+
+	packetType, reply, err := tds.readPacket()
+	if err != nil {
+		return nil, err
+	}
+	if packetType != tdsPacketReply {
+		return nil, fmt.Errorf("mssql: unexpected packet type: %d", packetType)
+	}
+
+	encryption, ok := tdsOption(reply, tdsOptionEncryption)
+	if !ok || len(encryption) != 1 {
+		return nil, errors.New("mssql: no encryption option in pre-login reply")
+	}
+	if encryption[0] == tdsEncryptNotSup {
+		return nil, refused("mssql", "encryption not supported")
+	}
+
+	conn.SetDeadline(time.Time{})
+	return tds, nil
+}
+
+// tdsOption returns the data of a pre-login option
+func tdsOption(payload []byte, option byte) ([]byte, bool) {
+	for i := 0; i+5 <= len(payload) && payload[i] != tdsOptionTerminator; i += 5 {
+		offset := int(binary.BigEndian.Uint16(payload[i+1 : i+3]))
+		length := int(binary.BigEndian.Uint16(payload[i+3 : i+5]))
+		if payload[i] != option {
+			continue
+		}
+		if offset+length > len(payload) {
+			return nil, false
+		}
+		return payload[offset : offset+length], true
+	}
+	return nil, false
+}
+
+// tdsConn wraps writes in TDS packets and unwraps reads
+type tdsConn struct {
+	net.Conn
+	packetType byte
+	packetID   byte
+	remaining  int
+}
+
+// Write sends the data as a single TDS packet
+func (t *tdsConn) Write(b []byte) (int, error) {
+	t.packetID++
+	header := []byte{t.packetType, tdsStatusEOM, 0, 0, 0, 0, t.packetID, 0}
+	binary.BigEndian.PutUint16(header[2:4], uint16(len(b)+8))
+
+	if _, err := t.Conn.Write(append(header, b...)); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Read returns the payload of the current TDS packet
+func (t *tdsConn) Read(b []byte) (int, error) {
+	if t.remaining == 0 {
+		header := make([]byte, 8)
+		if _, err := io.ReadFull(t.Conn, header); err != nil {
+			return 0, err
+		}
+		t.remaining = int(binary.BigEndian.Uint16(header[2:4])) - 8
+		if t.remaining < 0 {
+			return 0, errors.New("mssql: invalid packet length")
+		}
+	}
+
+	if len(b) > t.remaining {
+		b = b[:t.remaining]
+	}
+	n, err := io.ReadFull(t.Conn, b)
+	t.remaining -= n
+	return n, err
+}
+
+// readPacket reads a complete TDS packet
+func (t *tdsConn) readPacket() (byte, []byte, error) {
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(t.Conn, header); err != nil {
+		return 0, nil, err
+	}
+	length := int(binary.BigEndian.Uint16(header[2:4])) - 8
+	if length < 0 {
+		return 0, nil, errors.New("mssql: invalid packet length")
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(t.Conn, payload); err != nil {
+		return 0, nil, err
+	}
+	return header[0], payload, nil
+}
@@ -0,0 +1,101 @@
+package starttls
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// mysqlGreeting returns a server greeting packet advertising the capabilities
+func mysqlGreeting(capabilities uint16) []byte {
+	payload := []byte{0x0a}
+	payload = append(payload, "8.0.30\x00"...)
+	payload = append(payload, 0x01, 0x00, 0x00, 0x00)          // thread id
+	payload = append(payload, bytes.Repeat([]byte{'a'}, 8)...) // auth data
+	payload = append(payload, 0x00)                            // filler
+	payload = append(payload, byte(capabilities), byte(capabilities>>8))
+	return append([]byte{byte(len(payload)), 0x00, 0x00, 0x00}, payload...)
+}
+
+// tdsPrelogin returns a pre-login reply packet with the encryption option, or without options
+func tdsPrelogin(encryption int) []byte {
+	payload := []byte{
+		0x00, 0x00, 0x0b, 0x00, 0x06,
+		0x01, 0x00, 0x11, 0x00, 0x01,
+		0xff,
+		0x0f, 0x00, 0x07, 0xd0, 0x00, 0x00,
+		byte(encryption),
+	}
+	if encryption < 0 {
+		payload = []byte{0xff}
+	}
+	return append([]byte{0x04, 0x01, 0x00, byte(8 + len(payload)), 0x00, 0x00, 0x01, 0x00}, payload...)
+}
+
+var (
+	sslRequest = []byte{0x00, 0x00, 0x00, 0x08, 0x04, 0xd2, 0x16, 0x2f}
+
+	// 32 byte SSL request with sequence 1, the client capabilities, a 16 MiB packet size and utf8_general_ci
+	mysqlSSLRequest = append([]byte{
+		0x20, 0x00, 0x00, 0x01,
+		0x05, 0xaa, 0x08, 0x00,
+		0x00, 0x00, 0x00, 0x01,
+		0x21,
+	}, make([]byte, 23)...)
+
+	tdsPreloginRequest = []byte{
+		0x12, 0x01, 0x00, 0x1a, 0x00, 0x00, 0x01, 0x00,
+		0x00, 0x00, 0x0b, 0x00, 0x06,
+		0x01, 0x00, 0x11, 0x00, 0x01,
+		0xff,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x01,
+	}
+)
+
+func TestDatabaseNegotiators(t *testing.T) {
+	runNegotiations(t, []negotiation{
+		{"postgresql", PostgreSQL{}, []exchange{{sslRequest, []byte("S")}}, "ok"},
+		{"postgresql refused", PostgreSQL{}, []exchange{{sslRequest, []byte("N")}}, "refused"},
+		{"postgresql error", PostgreSQL{}, []exchange{{sslRequest, []byte("E")}}, "error"},
+		{"mysql", MySQL{}, []exchange{
+			{nil, mysqlGreeting(0xffff)},
+			{mysqlSSLRequest, nil},
+		}, "ok"},
+		{"mysql without ssl", MySQL{}, []exchange{{nil, mysqlGreeting(0xffff &^ 0x0800)}}, "refused"},
+		{"mysql error packet", MySQL{}, []exchange{{nil, []byte{0x09, 0x00, 0x00, 0x00, 0xff, 0x6a, 0x04, 'b', 'l', 'o', 'c', 'k', 'd'}}}, "refused"},
+		{"mysql not a greeting", MySQL{}, []exchange{{nil, []byte{0x01, 0x00, 0x00, 0x00, 0x09}}}, "error"},
+		{"mssql", MSSQL{}, []exchange{{tdsPreloginRequest, tdsPrelogin(0x01)}}, "ok"},
+		{"mssql encryption off", MSSQL{}, []exchange{{tdsPreloginRequest, tdsPrelogin(0x00)}}, "ok"},
+		{"mssql not supported", MSSQL{}, []exchange{{tdsPreloginRequest, tdsPrelogin(0x02)}}, "refused"},
+		{"mssql no encryption option", MSSQL{}, []exchange{{tdsPreloginRequest, tdsPrelogin(-1)}}, "error"},
+	})
+}
+
+func TestMSSQLWrapsHandshake(t *testing.T) {
+	hello := []byte{0x16, 0x03, 0x01, 0x00, 0x01, 0x01}
+	serverHello := []byte{0x16, 0x03, 0x03, 0x00, 0x01, 0x02}
+
+	conn, served, err := negotiate(t, MSSQL{}, []exchange{
+		{tdsPreloginRequest, tdsPrelogin(0x01)},
+		// The client hello is sent in the next pre-login packet, the server hello comes back in one
+		{append([]byte{0x12, 0x01, 0x00, 0x0e, 0x00, 0x00, 0x02, 0x00}, hello...), append([]byte{0x12, 0x01, 0x00, 0x0e, 0x00, 0x00, 0x01, 0x00}, serverHello...)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := conn.Write(hello); err != nil {
+		t.Fatal(err)
+	}
+	got := make([]byte, len(serverHello))
+	if _, err := io.ReadFull(conn, got); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, serverHello) {
+		t.Errorf("read %x, want %x", got, serverHello)
+	}
+	if err := <-served; err != nil {
+		t.Error(err)
+	}
+}
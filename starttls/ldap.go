@@ -0,0 +1,109 @@
+package starttls
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// ldapStartTLSOID is the name of the StartTLS extended operation
+const ldapStartTLSOID = "1.3.6.1.4.1.1466.20037"
+
+// ldapNoticeOfDisconnectionOID is the name of the unsolicited notice sent before a server closes the connection
+const ldapNoticeOfDisconnectionOID = "1.3.6.1.4.1.1466.20036"
+
+// LDAP protocol operations
+const (
+	ldapExtendedRequest  = 0x77 // [APPLICATION 23]
+	ldapExtendedResponse = 0x78 // [APPLICATION 24]
+	ldapRequestName      = 0x80 // [0]
+	ldapResponseName     = 0x8a // [10]
+)
+
+// ldapResultCodes names the result codes LDAP servers refuse StartTLS with
+var ldapResultCodes = map[int]string{
+	1:  "operationsError",
+	2:  "protocolError",
+	8:  "strongerAuthRequired",
+	12: "unavailableCriticalExtension",
+	51: "busy",
+	52: "unavailable",
+	53: "unwillingToPerform",
+	80: "other",
+}
+
+// LDAP upgrades LDAP connections using the StartTLS extended operation
+type LDAP struct{}
+
+// Negotiate implements Negotiator
+func (LDAP) Negotiate(conn net.Conn, hostname string) (net.Conn, error) {
+	conn.SetDeadline(time.Now().Add(Timeout))
+
+	request := berEncode(ldapExtendedRequest, berEncode(ldapRequestName, []byte(ldapStartTLSOID)))
+	message := berEncode(berSequence, append(berInt(berInteger, 1), request...))
+	if _, err := conn.Write(message); err != nil {
+		return nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+	reply, err := berRead(reader)
+	if err != nil {
+		return nil, err
+	}
+	if reply.tag != berSequence {
+		return nil, fmt.Errorf("ldap: unexpected reply tag: %#x", reply.tag)
+	}
+	fields, err := berChildren(reply.content)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) < 2 || fields[0].tag != berInteger || fields[1].tag != ldapExtendedResponse {
+		return nil, errors.New("ldap: unexpected reply")
+	}
+
+	// LDAPResult: resultCode, matchedDN, diagnosticMessage, then the optional responseName
+	result, err := berChildren(fields[1].content)
+	if err != nil {
+		return nil, err
+	}
+	if len(result) < 3 || result[0].tag != berEnumerated {
+		return nil, errors.New("ldap: invalid extended response")
+	}
+	code, err := berToInt(result[0].content)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range result[3:] {
+		if e.tag == ldapResponseName && string(e.content) == ldapNoticeOfDisconnectionOID {
+			return nil, refused("ldap", fmt.Sprintf("notice of disconnection: %s (%d) %s", ldapResultName(code), code, result[2].content))
+		}
+	}
+
+	id, err := berToInt(fields[0].content)
+	if err != nil {
+		return nil, err
+	}
+	if id != 1 {
+		return nil, fmt.Errorf("ldap: unexpected message ID: %d", id)
+	}
+	if code != 0 {
+		return nil, refused("ldap", fmt.Sprintf("%s (%d) %s", ldapResultName(code), code, result[2].content))
+	}
+
+	if reader.Buffered() > 0 {
+		return nil, errors.New("unexpected data after upgrade")
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+// ldapResultName returns the name of an LDAP result code
+func ldapResultName(code int) string {
+	if name, ok := ldapResultCodes[code]; ok {
+		return name
+	}
+	return "resultCode"
+}
@@ -0,0 +1,28 @@
+package starttls
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func unhex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// StartTLS extended request with message ID 1
+var ldapRequest = unhex("301d02010177188016312e332e362e312e342e312e313436362e3230303337")
+
+func TestLDAP(t *testing.T) {
+	runNegotiations(t, []negotiation{
+		{"ldap success", LDAP{}, []exchange{{ldapRequest, unhex("300c02010178070a010004000400")}}, "ok"},
+		{"ldap success with response name", LDAP{}, []exchange{{ldapRequest, unhex("3024020101781f0a0100040004008a16312e332e362e312e342e312e313436362e3230303337")}}, "ok"},
+		{"ldap protocol error", LDAP{}, []exchange{{ldapRequest, unhex("300e02010178090a0102040004026e6f")}}, "refused"},
+		{"ldap notice of disconnection", LDAP{}, []exchange{{ldapRequest, unhex("3024020100781f0a0134040004008a16312e332e362e312e342e312e313436362e3230303336")}}, "refused"},
+		{"ldap wrong message id", LDAP{}, []exchange{{ldapRequest, unhex("300c02010278070a010004000400")}}, "error"},
+		{"ldap not ber", LDAP{}, []exchange{{ldapRequest, []byte("HTTP/1.1 400\r\n")}}, "error"},
+	})
+}
@@ -0,0 +1,128 @@
+package starttls
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// SMTP upgrades SMTP connections using EHLO and STARTTLS
+type SMTP struct {
+	// Domain is sent with EHLO, defaults to "localhost"
+	Domain string
+}
+
+// Negotiate implements Negotiator
+func (s SMTP) Negotiate(conn net.Conn, hostname string) (net.Conn, error) {
+	l := newLineConn(conn)
+
+	code, lines, err := l.readReply()
+	if err != nil {
+		return nil, err
+	}
+	if code != "220" {
+		return nil, fmt.Errorf("smtp: unexpected greeting: %s", strings.Join(lines, " "))
+	}
+
+	domain := s.Domain
+	if domain == "" {
+		domain = "localhost"
+	}
+	if err := l.writeLine("EHLO " + domain); err != nil {
+		return nil, err
+	}
+	code, lines, err = l.readReply()
+	if err != nil {
+		return nil, err
+	}
+	if code != "250" {
+		return nil, refused("smtp", strings.Join(lines, " "))
+	}
+
+	supported := false
+	for _, line := range lines {
+		if len(line) > 4 && strings.EqualFold(strings.TrimSpace(line[4:]), "STARTTLS") {
+			supported = true
+		}
+	}
+	if !supported {
+		return nil, refused("smtp", "STARTTLS not advertised")
+	}
+
+	if err := l.writeLine("STARTTLS"); err != nil {
+		return nil, err
+	}
+	code, lines, err = l.readReply()
+	if err != nil {
+		return nil, err
+	}
+	if code != "220" {
+		return nil, refused("smtp", strings.Join(lines, " "))
+	}
+
+	return l.done()
+}
+
+// IMAP upgrades IMAP connections using STARTTLS
+type IMAP struct{}
+
+// Negotiate implements Negotiator
+func (IMAP) Negotiate(conn net.Conn, hostname string) (net.Conn, error) {
+	l := newLineConn(conn)
+
+	greeting, err := l.readLine()
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(greeting, "* OK") {
+		return nil, fmt.Errorf("imap: unexpected greeting: %s", greeting)
+	}
+
+	if err := l.writeLine("a001 STARTTLS"); err != nil {
+		return nil, err
+	}
+
+	// Skip untagged responses until the tagged one
+	for {
+		line, err := l.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(line, "a001 ") {
+			continue
+		}
+		if !strings.HasPrefix(strings.ToUpper(line), "A001 OK") {
+			return nil, refused("imap", line)
+		}
+		return l.done()
+	}
+}
+
+// POP3 upgrades POP3 connections using STLS
+type POP3 struct{}
+
+// Negotiate implements Negotiator
+func (POP3) Negotiate(conn net.Conn, hostname string) (net.Conn, error) {
+	l := newLineConn(conn)
+
+	greeting, err := l.readLine()
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(greeting, "+OK") {
+		return nil, fmt.Errorf("pop3: unexpected greeting: %s", greeting)
+	}
+
+	if err := l.writeLine("STLS"); err != nil {
+		return nil, err
+	}
+	reply, err := l.readLine()
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(reply, "+OK") {
+		return nil, refused("pop3", reply)
+	}
+
+	return l.done()
+}
@@ -0,0 +1,54 @@
+package starttls
+
+import "testing"
+
+func TestMailNegotiators(t *testing.T) {
+	runNegotiations(t, []negotiation{
+		{"smtp", SMTP{}, []exchange{
+			line("", "220 mx.example.com ESMTP\r\n"),
+			line("EHLO localhost", "250-mx.example.com\r\n250-PIPELINING\r\n250 STARTTLS\r\n"),
+			line("STARTTLS", "220 2.0.0 Ready to start TLS\r\n"),
+		}, "ok"},
+		{"smtp domain", SMTP{Domain: "scanner.example.org"}, []exchange{
+			line("", "220 mx.example.com ESMTP\r\n"),
+			line("EHLO scanner.example.org", "250-mx.example.com\r\n250 starttls\r\n"),
+			line("STARTTLS", "220 Go ahead\r\n"),
+		}, "ok"},
+		{"smtp not advertised", SMTP{}, []exchange{
+			line("", "220 mx.example.com ESMTP\r\n"),
+			line("EHLO localhost", "250-mx.example.com\r\n250 8BITMIME\r\n"),
+		}, "refused"},
+		{"smtp rejected", SMTP{}, []exchange{
+			line("", "220 mx.example.com ESMTP\r\n"),
+			line("EHLO localhost", "250 STARTTLS\r\n"),
+			line("STARTTLS", "454 4.7.0 TLS not available\r\n"),
+		}, "refused"},
+		{"smtp greeting", SMTP{}, []exchange{
+			line("", "554 No service\r\n"),
+		}, "error"},
+		{"smtp data after upgrade", SMTP{}, []exchange{
+			line("", "220 mx.example.com ESMTP\r\n"),
+			line("EHLO localhost", "250 STARTTLS\r\n"),
+			line("STARTTLS", "220 Ready\r\n250 injected\r\n"),
+		}, "error"},
+		{"imap", IMAP{}, []exchange{
+			line("", "* OK [CAPABILITY IMAP4rev1 STARTTLS] ready\r\n"),
+			line("a001 STARTTLS", "* BYE not really\r\na001 OK Begin TLS negotiation now\r\n"),
+		}, "ok"},
+		{"imap rejected", IMAP{}, []exchange{
+			line("", "* OK ready\r\n"),
+			line("a001 STARTTLS", "a001 BAD unknown command\r\n"),
+		}, "refused"},
+		{"imap greeting", IMAP{}, []exchange{
+			line("", "* BYE go away\r\n"),
+		}, "error"},
+		{"pop3", POP3{}, []exchange{
+			line("", "+OK POP3 ready\r\n"),
+			line("STLS", "+OK Begin TLS\r\n"),
+		}, "ok"},
+		{"pop3 rejected", POP3{}, []exchange{
+			line("", "+OK POP3 ready\r\n"),
+			line("STLS", "-ERR Command not permitted\r\n"),
+		}, "refused"},
+	})
+}
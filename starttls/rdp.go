@@ -0,0 +1,108 @@
+package starttls
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// RDP negotiation types and protocols
+const (
+	rdpNegRequest  = 0x01
+	rdpNegResponse = 0x02
+	rdpNegFailure  = 0x03
+
+	rdpProtocolRDP    = 0x00000000
+	rdpProtocolSSL    = 0x00000001
+	rdpProtocolHybrid = 0x00000002
+
+	x224ConnectionConfirm = 0xd0
+)
+
+// rdpFailureCodes names the failure codes of an RDP negotiation failure
+var rdpFailureCodes = map[uint32]string{
+	1: "SSL_REQUIRED_BY_SERVER",
+	2: "SSL_NOT_ALLOWED_BY_SERVER",
+	3: "SSL_CERT_NOT_ON_SERVER",
+	4: "INCONSISTENT_FLAGS",
+	5: "HYBRID_REQUIRED_BY_SERVER",
+	6: "SSL_WITH_USER_AUTH_REQUIRED_BY_SERVER",
+}
+
+// NegotiationFailure is returned when a server answers the upgrade with a failure PDU
+type NegotiationFailure struct {
+	Protocol string
+	Code     uint32
+	Reason   string
+}
+
+func (e *NegotiationFailure) Error() string {
+	return fmt.Sprintf("%s: negotiation failure %d (%s)", e.Protocol, e.Code, e.Reason)
+}
+
+// RDP upgrades RDP connections using an X.224 Connection Request with an RDP Negotiation Request
+//
+// Both TLS and CredSSP are requested, since CredSSP servers also start with
+// a TLS handshake. Servers answering with a negotiation failure PDU result
+// in a *NegotiationFailure error.
+type RDP struct{}
+
+// Negotiate implements Negotiator
+func (RDP) Negotiate(conn net.Conn, hostname string) (net.Conn, error) {
+	conn.SetDeadline(time.Now().Add(Timeout))
+
+	request := []byte{
+		// TPKT header
+		0x03, 0x00, 0x00, 0x13,
+		// X.224 Connection Request
+		0x0e, 0xe0, 0x00, 0x00, 0x00, 0x00, 0x00,
+		// RDP Negotiation Request
+		rdpNegRequest, 0x00, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}
+	binary.LittleEndian.PutUint32(request[15:19], rdpProtocolSSL|rdpProtocolHybrid)
+
+	if _, err := conn.Write(request); err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return nil, err
+	}
+	if header[0] != 0x03 {
+		return nil, errors.New("rdp: not a TPKT reply")
+	}
+	length := int(binary.BigEndian.Uint16(header[2:4])) - 4
+	if length < 7 {
+		return nil, errors.New("rdp: reply too short")
+	}
+	reply := make([]byte, length)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return nil, err
+	}
+	if reply[1]&0xf0 != x224ConnectionConfirm {
+		return nil, fmt.Errorf("rdp: unexpected X.224 reply: %#x", reply[1])
+	}
+
+	// Servers without negotiation data only support standard RDP security
+	neg := reply[7:]
+	if len(neg) < 8 {
+		return nil, refused("rdp", "standard RDP security only")
+	}
+
+	value := binary.LittleEndian.Uint32(neg[4:8])
+	switch neg[0] {
+	case rdpNegFailure:
+		return nil, &NegotiationFailure{Protocol: "rdp", Code: value, Reason: rdpFailureCodes[value]}
+	case rdpNegResponse:
+		if value == rdpProtocolRDP {
+			return nil, refused("rdp", "standard RDP security selected")
+		}
+		conn.SetDeadline(time.Time{})
+		return conn, nil
+	}
+	return nil, fmt.Errorf("rdp: unexpected negotiation type: %d", neg[0])
+}
@@ -0,0 +1,44 @@
+package starttls
+
+import (
+	"errors"
+	"testing"
+)
+
+var rdpRequest = []byte{
+	0x03, 0x00, 0x00, 0x13,
+	0x0e, 0xe0, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x01, 0x00, 0x08, 0x00, 0x03, 0x00, 0x00, 0x00,
+}
+
+// rdpConfirm returns an X.224 Connection Confirm with a negotiation PDU of the type and value
+func rdpConfirm(negType byte, value byte) []byte {
+	return []byte{
+		0x03, 0x00, 0x00, 0x13,
+		0x0e, 0xd0, 0x00, 0x00, 0x12, 0x34, 0x00,
+		negType, 0x00, 0x08, 0x00, value, 0x00, 0x00, 0x00,
+	}
+}
+
+func TestRDP(t *testing.T) {
+	runNegotiations(t, []negotiation{
+		{"rdp ssl", RDP{}, []exchange{{rdpRequest, rdpConfirm(rdpNegResponse, 0x01)}}, "ok"},
+		{"rdp hybrid", RDP{}, []exchange{{rdpRequest, rdpConfirm(rdpNegResponse, 0x02)}}, "ok"},
+		{"rdp standard security", RDP{}, []exchange{{rdpRequest, rdpConfirm(rdpNegResponse, 0x00)}}, "refused"},
+		{"rdp no negotiation data", RDP{}, []exchange{{rdpRequest, []byte{0x03, 0x00, 0x00, 0x0b, 0x06, 0xd0, 0x00, 0x00, 0x12, 0x34, 0x00}}}, "refused"},
+		{"rdp not tpkt", RDP{}, []exchange{{rdpRequest, []byte("HTTP/1.1 400\r\n")}}, "error"},
+		{"rdp disconnect request", RDP{}, []exchange{{rdpRequest, []byte{0x03, 0x00, 0x00, 0x0b, 0x06, 0x80, 0x00, 0x00, 0x12, 0x34, 0x00}}}, "error"},
+	})
+
+	_, _, err := negotiate(t, RDP{}, []exchange{{rdpRequest, rdpConfirm(rdpNegFailure, 0x05)}})
+	failure := &NegotiationFailure{}
+	if !errors.As(err, &failure) {
+		t.Fatalf("negotiation failure PDU: got %v", err)
+	}
+	if failure.Protocol != "rdp" || failure.Code != 5 || failure.Reason != "HYBRID_REQUIRED_BY_SERVER" {
+		t.Errorf("negotiation failure = %+v", failure)
+	}
+	if errors.Is(err, ErrRefused) {
+		t.Error("negotiation failure wraps ErrRefused")
+	}
+}
@@ -0,0 +1,175 @@
+package starttls
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Script directives
+const (
+	ScriptSend     = "send"
+	ScriptExpect   = "expect"
+	ScriptExpectRe = "expect-re"
+	ScriptFail     = "fail"
+	ScriptFailRe   = "fail-re"
+	ScriptTimeout  = "timeout"
+)
+
+// builtinScripts are the scripts returned by ByName
+var builtinScripts = map[string]string{
+	// RFC 4217
+	"ftp": `
+fail-re ^[45]\d\d[ -]
+expect-re ^220\s
+send AUTH TLS
+expect 234
+`,
+	// RFC 4642
+	"nntp": `
+fail-re ^[45]\d\d[ -]
+expect-re ^20[01]
+send STARTTLS
+expect 382
+`,
+	// IRCv3 tls extension
+	"irc": `
+fail ERROR
+fail-re ^:\S+ 691\s
+send STARTTLS
+expect-re ^:\S+ 670\s
+`,
+}
+
+// ScriptStep is a single directive of a script
+type ScriptStep struct {
+	Directive string
+	Arg       string
+	Timeout   time.Duration
+	re        *regexp.Regexp
+}
+
+// Script upgrades line based protocols by following a send/expect script
+//
+// Scripts hold one directive per line, empty lines and lines starting with
+// '#' are ignored:
+//
+//	send <line>        sends the line, "{host}" is replaced with the hostname
+//	expect <prefix>    reads lines until one starts with the prefix
+//	expect-re <regexp> reads lines until one matches the expression
+//	fail <prefix>      refuses the upgrade if a later expect reads a line starting with the prefix
+//	fail-re <regexp>   refuses the upgrade if a later expect reads a line matching the expression
+//	timeout <duration> sets the time allowed for later expects, such as "10s"
+type Script struct {
+	Name  string
+	Steps []ScriptStep
+}
+
+// ParseScript parses a send/expect script
+func ParseScript(name string, text string) (*Script, error) {
+	s := &Script{Name: name}
+	timeout := Timeout
+
+	for n, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		directive, arg, _ := strings.Cut(strings.TrimLeft(line, " \t"), " ")
+		step := ScriptStep{Directive: strings.ToLower(directive), Arg: arg}
+
+		switch step.Directive {
+		case ScriptSend, ScriptExpect, ScriptFail:
+			if arg == "" && step.Directive != ScriptSend {
+				return nil, fmt.Errorf("%s: line %d: missing argument", name, n+1)
+			}
+		case ScriptExpectRe, ScriptFailRe:
+			re, err := regexp.Compile(arg)
+			if err != nil {
+				return nil, fmt.Errorf("%s: line %d: %s", name, n+1, err)
+			}
+			step.re = re
+		case ScriptTimeout:
+			d, err := time.ParseDuration(arg)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("%s: line %d: invalid timeout: %s", name, n+1, arg)
+			}
+			timeout = d
+			continue
+		default:
+			return nil, fmt.Errorf("%s: line %d: unknown directive: %s", name, n+1, directive)
+		}
+
+		step.Timeout = timeout
+		s.Steps = append(s.Steps, step)
+	}
+
+	if len(s.Steps) == 0 {
+		return nil, fmt.Errorf("%s: empty script", name)
+	}
+	return s, nil
+}
+
+// LoadScript reads a send/expect script from a file, named after the file
+func LoadScript(path string) (*Script, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseScript(path, string(data))
+}
+
+// Negotiate implements Negotiator
+func (s *Script) Negotiate(conn net.Conn, hostname string) (net.Conn, error) {
+	l := &lineConn{conn: conn, reader: bufio.NewReader(conn)}
+	fails := []ScriptStep{}
+
+	for _, step := range s.Steps {
+		switch step.Directive {
+		case ScriptSend:
+			conn.SetDeadline(time.Now().Add(step.Timeout))
+			if err := l.writeLine(strings.ReplaceAll(step.Arg, "{host}", hostname)); err != nil {
+				return nil, err
+			}
+		case ScriptFail, ScriptFailRe:
+			fails = append(fails, step)
+		case ScriptExpect, ScriptExpectRe:
+			conn.SetDeadline(time.Now().Add(step.Timeout))
+			if err := s.expect(l, step, fails); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return l.done()
+}
+
+// expect reads lines until one matches the step, or one of the fail steps
+func (s *Script) expect(l *lineConn, step ScriptStep, fails []ScriptStep) error {
+	for {
+		line, err := l.readLine()
+		if err != nil {
+			return fmt.Errorf("%s: waiting for %q: %w", s.Name, step.Arg, err)
+		}
+		for _, f := range fails {
+			if f.matches(line) {
+				return refused(s.Name, line)
+			}
+		}
+		if step.matches(line) {
+			return nil
+		}
+	}
+}
+
+// matches reports whether a line matches an expect or fail step
+func (step ScriptStep) matches(line string) bool {
+	if step.re != nil {
+		return step.re.MatchString(line)
+	}
+	return strings.HasPrefix(line, step.Arg)
+}
@@ -0,0 +1,95 @@
+package starttls
+
+import (
+	"testing"
+	"time"
+)
+
+func builtin(t *testing.T, name string) Negotiator {
+	t.Helper()
+	n, err := ByName(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestBuiltinScripts(t *testing.T) {
+	runNegotiations(t, []negotiation{
+		{"ftp", builtin(t, "ftp"), []exchange{
+			line("", "220 FTP server ready\r\n"),
+			line("AUTH TLS", "234 AUTH TLS successful\r\n"),
+		}, "ok"},
+		{"ftp multi-line greeting", builtin(t, "ftp"), []exchange{
+			// Continuation lines starting with 4 or 5 are not reply codes
+			line("", "220-Welcome\r\n5 mirrors available\r\n4 of them in Europe\r\n220 Ready\r\n"),
+			line("AUTH TLS", "234 Proceed\r\n"),
+		}, "ok"},
+		{"ftp refused", builtin(t, "ftp"), []exchange{
+			line("", "220 FTP server ready\r\n"),
+			line("AUTH TLS", "530 Please login with USER and PASS\r\n"),
+		}, "refused"},
+		{"ftp unavailable", builtin(t, "ftp"), []exchange{
+			line("", "421-Too many connections\r\n421 Try again later\r\n"),
+		}, "refused"},
+		{"nntp", builtin(t, "nntp"), []exchange{
+			line("", "200 news.example.com ready\r\n"),
+			line("STARTTLS", "382 Continue with TLS negotiation\r\n"),
+		}, "ok"},
+		{"nntp refused", builtin(t, "nntp"), []exchange{
+			line("", "201 news.example.com ready, no posting\r\n"),
+			line("STARTTLS", "502 Command unavailable\r\n"),
+		}, "refused"},
+		{"irc", builtin(t, "irc"), []exchange{
+			line("STARTTLS", ":irc.example.com NOTICE * :*** Looking up your hostname\r\n:irc.example.com 670 * :STARTTLS successful\r\n"),
+		}, "ok"},
+		{"irc failed", builtin(t, "irc"), []exchange{
+			line("STARTTLS", ":irc.example.com 691 * :STARTTLS failed\r\n"),
+		}, "refused"},
+	})
+}
+
+func TestScripts(t *testing.T) {
+	script, err := ParseScript("custom", `
+# greeting, then upgrade with the hostname
+timeout 2s
+fail -ERR
+expect +OK
+send UPGRADE {host}
+expect-re ^\+OK (tls|TLS)$
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(script.Steps) != 4 || script.Steps[0].Timeout != 2*time.Second {
+		t.Fatalf("ParseScript = %+v", script.Steps)
+	}
+
+	runNegotiations(t, []negotiation{
+		{"custom", script, []exchange{
+			line("", "+OK ready\r\n"),
+			line("UPGRADE example.com", "* comment\r\n+OK TLS\r\n"),
+		}, "ok"},
+		{"custom refused", script, []exchange{
+			line("", "+OK ready\r\n"),
+			line("UPGRADE example.com", "-ERR no\r\n"),
+		}, "refused"},
+		{"custom closed", script, []exchange{
+			line("", "* busy\r\n"),
+		}, "error"},
+	})
+
+	for _, text := range []string{
+		"",
+		"# only comments",
+		"expect",
+		"expect-re (",
+		"timeout soon",
+		"timeout -1s",
+		"wait 5",
+	} {
+		if _, err := ParseScript("invalid", text); err == nil {
+			t.Errorf("ParseScript(%q) accepted an invalid script", text)
+		}
+	}
+}
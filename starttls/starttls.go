@@ -0,0 +1,114 @@
+package starttls
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// Negotiator upgrades a fresh connection to TLS before the probe is sent
+type Negotiator interface {
+	// Negotiate runs the plaintext part of the protocol on the connection.
+	// The returned connection is used to send the probe and read the server
+	// hello, which allows negotiators to wrap the TLS records.
+	Negotiate(conn net.Conn, hostname string) (net.Conn, error)
+}
+
+// ErrRefused is wrapped by the errors of servers refusing the upgrade
+var ErrRefused = errors.New("upgrade refused")
+
+// Timeout is the time allowed for the plaintext negotiation
+var Timeout = time.Second * 5
+
+// ByName returns the built-in negotiator for a protocol, such as "smtp"
+func ByName(name string) (Negotiator, error) {
+	switch strings.ToLower(name) {
+	case "smtp":
+		return SMTP{}, nil
+	case "imap":
+		return IMAP{}, nil
+	case "pop3":
+		return POP3{}, nil
+	case "postgres", "postgresql":
+		return PostgreSQL{}, nil
+	case "mysql":
+		return MySQL{}, nil
+	case "mssql", "tds":
+		return MSSQL{}, nil
+	case "rdp":
+		return RDP{}, nil
+	case "ldap":
+		return LDAP{}, nil
+	case "xmpp", "xmpp-client":
+		return XMPP{}, nil
+	case "xmpp-server":
+		return XMPP{Server: true}, nil
+	}
+	if text, ok := builtinScripts[strings.ToLower(name)]; ok {
+		script, err := ParseScript(strings.ToLower(name), text)
+		if err != nil {
+			return nil, err
+		}
+		return script, nil
+	}
+	return nil, fmt.Errorf("unknown starttls protocol: %s", name)
+}
+
+// refused returns an error for a server refusing the upgrade
+func refused(protocol string, reply string) error {
+	return fmt.Errorf("%w: %s: %s", ErrRefused, protocol, strings.TrimSpace(reply))
+}
+
+// lineConn is a connection used for line based negotiations
+type lineConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func newLineConn(conn net.Conn) *lineConn {
+	conn.SetDeadline(time.Now().Add(Timeout))
+	return &lineConn{conn: conn, reader: bufio.NewReader(conn)}
+}
+
+// done clears the deadline and returns the connection for the probe
+func (l *lineConn) done() (net.Conn, error) {
+	if l.reader.Buffered() > 0 {
+		return nil, errors.New("unexpected data after upgrade")
+	}
+	l.conn.SetDeadline(time.Time{})
+	return l.conn, nil
+}
+
+func (l *lineConn) readLine() (string, error) {
+	line, err := l.reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (l *lineConn) writeLine(line string) error {
+	_, err := l.conn.Write([]byte(line + "\r\n"))
+	return err
+}
+
+// readReply reads a, possibly multi-line, reply with a three digit code, as used by SMTP and FTP
+func (l *lineConn) readReply() (string, []string, error) {
+	lines := []string{}
+	for {
+		line, err := l.readLine()
+		if err != nil {
+			return "", lines, err
+		}
+		if len(line) < 3 {
+			return "", lines, fmt.Errorf("invalid reply: %q", line)
+		}
+		lines = append(lines, line)
+		if len(line) == 3 || line[3] != '-' {
+			return line[:3], lines, nil
+		}
+	}
+}
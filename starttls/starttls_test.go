@@ -0,0 +1,108 @@
+package starttls
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+)
+
+// exchange is a step of a scripted server: it reads want from the client, then sends reply
+type exchange struct {
+	want  []byte
+	reply []byte
+}
+
+// line returns an exchange of text lines, as sent by line based protocols
+func line(want, reply string) exchange {
+	e := exchange{}
+	if want != "" {
+		e.want = []byte(want + "\r\n")
+	}
+	if reply != "" {
+		e.reply = []byte(reply)
+	}
+	return e
+}
+
+// negotiation is a table test of a negotiator against a scripted server
+type negotiation struct {
+	name       string
+	negotiator Negotiator
+	steps      []exchange
+	// want is "ok", "refused" for errors wrapping ErrRefused, or "error"
+	want string
+}
+
+// serve runs the scripted server on its end of a pipe
+func serve(conn net.Conn, steps []exchange) error {
+	defer conn.Close()
+	for i, step := range steps {
+		if step.want != nil {
+			got := make([]byte, len(step.want))
+			if _, err := io.ReadFull(conn, got); err != nil {
+				return fmt.Errorf("step %d: %s", i, err)
+			}
+			if !bytes.Equal(got, step.want) {
+				return fmt.Errorf("step %d: got %q, want %q", i, got, step.want)
+			}
+		}
+		if step.reply != nil {
+			if _, err := conn.Write(step.reply); err != nil {
+				return fmt.Errorf("step %d: %s", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// negotiate runs a negotiator against the scripted server and returns the upgraded connection
+func negotiate(t *testing.T, n Negotiator, steps []exchange) (net.Conn, chan error, error) {
+	t.Helper()
+	client, server := net.Pipe()
+	t.Cleanup(func() { client.Close() })
+
+	served := make(chan error, 1)
+	go func() { served <- serve(server, steps) }()
+
+	conn, err := n.Negotiate(client, "example.com")
+	return conn, served, err
+}
+
+func runNegotiations(t *testing.T, tests []negotiation) {
+	t.Helper()
+	for _, tt := range tests {
+		_, served, err := negotiate(t, tt.negotiator, tt.steps)
+
+		got := "ok"
+		if errors.Is(err, ErrRefused) {
+			got = "refused"
+		} else if err != nil {
+			got = "error"
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %s (%v), want %s", tt.name, got, err, tt.want)
+			continue
+		}
+
+		// Successful upgrades consume the whole script
+		if got == "ok" {
+			if err := <-served; err != nil {
+				t.Errorf("%s: server: %s", tt.name, err)
+			}
+		}
+	}
+}
+
+func TestByName(t *testing.T) {
+	for _, name := range []string{"smtp", "IMAP", "pop3", "postgres", "mysql", "tds", "rdp", "ldap", "xmpp", "xmpp-server", "ftp", "nntp", "irc"} {
+		if n, err := ByName(name); err != nil || n == nil {
+			t.Errorf("ByName(%s) = %v, %v", name, n, err)
+		}
+	}
+	if _, err := ByName("gopher"); err == nil {
+		t.Error("ByName accepted an unknown protocol")
+	}
+}
@@ -0,0 +1,153 @@
+package starttls
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// XMPP namespaces
+const (
+	xmppStreamNS = "http://etherx.jabber.org/streams"
+	xmppTLSNS    = "urn:ietf:params:xml:ns:xmpp-tls"
+	xmppClientNS = "jabber:client"
+	xmppServerNS = "jabber:server"
+)
+
+// XMPP upgrades XMPP connections using the <starttls/> stream feature
+type XMPP struct {
+	// Domain is sent as the stream's "to" address, defaults to the hostname
+	Domain string
+	// Server opens a server-to-server stream instead of a client stream
+	Server bool
+}
+
+// xmppFeatures is the <stream:features/> element
+type xmppFeatures struct {
+	StartTLS *struct {
+		Required *struct{} `xml:"required"`
+	} `xml:"urn:ietf:params:xml:ns:xmpp-tls starttls"`
+}
+
+// xmppStreamError is the <stream:error/> element, holding a defined condition and optional text
+type xmppStreamError struct {
+	Conditions []struct {
+		XMLName xml.Name
+	} `xml:",any"`
+	Text string `xml:"text"`
+}
+
+// Negotiate implements Negotiator
+func (x XMPP) Negotiate(conn net.Conn, hostname string) (net.Conn, error) {
+	l := newLineConn(conn)
+
+	domain := x.Domain
+	if domain == "" {
+		domain = hostname
+	}
+	ns := xmppClientNS
+	if x.Server {
+		ns = xmppServerNS
+	}
+
+	header := fmt.Sprintf("<?xml version='1.0'?><stream:stream to='%s' xmlns='%s' xmlns:stream='%s' version='1.0'>",
+		xmlEscape(domain), ns, xmppStreamNS)
+	if _, err := conn.Write([]byte(header)); err != nil {
+		return nil, err
+	}
+
+	// The reader implements io.ByteReader, so the decoder does not read ahead of the stream
+	decoder := xml.NewDecoder(l.reader)
+
+	start, err := xmppNext(decoder)
+	if err != nil {
+		return nil, err
+	}
+	if start.Name.Space != xmppStreamNS || start.Name.Local != "stream" {
+		return nil, fmt.Errorf("xmpp: unexpected stream header: %s", start.Name.Local)
+	}
+
+	start, err = xmppNext(decoder)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case start.Name.Space == xmppStreamNS && start.Name.Local == "error":
+		return nil, xmppError(decoder, start)
+	case start.Name.Space != xmppStreamNS || start.Name.Local != "features":
+		return nil, fmt.Errorf("xmpp: unexpected element: %s", start.Name.Local)
+	}
+	features := xmppFeatures{}
+	if err := decoder.DecodeElement(&features, &start); err != nil {
+		return nil, err
+	}
+	if features.StartTLS == nil {
+		return nil, refused("xmpp", "starttls not offered")
+	}
+
+	if _, err := conn.Write([]byte("<starttls xmlns='" + xmppTLSNS + "'/>")); err != nil {
+		return nil, err
+	}
+
+	start, err = xmppNext(decoder)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case start.Name.Space == xmppTLSNS && start.Name.Local == "proceed":
+		if err := decoder.Skip(); err != nil {
+			return nil, err
+		}
+	case start.Name.Space == xmppTLSNS && start.Name.Local == "failure":
+		return nil, refused("xmpp", "starttls failure")
+	case start.Name.Space == xmppStreamNS && start.Name.Local == "error":
+		return nil, xmppError(decoder, start)
+	default:
+		return nil, fmt.Errorf("xmpp: unexpected element: %s", start.Name.Local)
+	}
+
+	return l.done()
+}
+
+// xmppNext returns the next start element of the stream
+func xmppNext(decoder *xml.Decoder) (xml.StartElement, error) {
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return xml.StartElement{}, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			return t, nil
+		case xml.EndElement:
+			if t.Name.Local == "stream" {
+				return xml.StartElement{}, errors.New("xmpp: stream closed by server")
+			}
+		}
+	}
+}
+
+// xmppError returns an upgrade refusal for a stream error
+func xmppError(decoder *xml.Decoder, start xml.StartElement) error {
+	streamError := xmppStreamError{}
+	if err := decoder.DecodeElement(&streamError, &start); err != nil {
+		return err
+	}
+	condition := "undefined-condition"
+	for _, c := range streamError.Conditions {
+		if c.XMLName.Local != "text" {
+			condition = c.XMLName.Local
+			break
+		}
+	}
+	return refused("xmpp", "stream error: "+condition+" "+streamError.Text)
+}
+
+// xmlEscape escapes a value for use in an attribute
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,48 @@
+package starttls
+
+import "testing"
+
+const (
+	xmppClientHeader = "<?xml version='1.0'?><stream:stream to='example.com' xmlns='jabber:client' xmlns:stream='http://etherx.jabber.org/streams' version='1.0'>"
+	xmppServerHeader = "<?xml version='1.0'?><stream:stream to='example.com' xmlns='jabber:server' xmlns:stream='http://etherx.jabber.org/streams' version='1.0'>"
+	xmppStartTLS     = "<starttls xmlns='urn:ietf:params:xml:ns:xmpp-tls'/>"
+
+	xmppStreamReply   = "<?xml version='1.0'?><stream:stream xmlns='jabber:client' xmlns:stream='http://etherx.jabber.org/streams' id='c2s-1' from='example.com' version='1.0'>"
+	xmppFeaturesReply = "<stream:features><starttls xmlns='urn:ietf:params:xml:ns:xmpp-tls'><required/></starttls><mechanisms xmlns='urn:ietf:params:xml:ns:xmpp-sasl'><mechanism>PLAIN</mechanism></mechanisms></stream:features>"
+	xmppProceedReply  = "<proceed xmlns='urn:ietf:params:xml:ns:xmpp-tls'/>"
+)
+
+func xmpp(want, reply string) exchange {
+	return exchange{[]byte(want), []byte(reply)}
+}
+
+func TestXMPP(t *testing.T) {
+	runNegotiations(t, []negotiation{
+		{"xmpp client", XMPP{}, []exchange{
+			xmpp(xmppClientHeader, xmppStreamReply+xmppFeaturesReply),
+			xmpp(xmppStartTLS, xmppProceedReply),
+		}, "ok"},
+		{"xmpp server", XMPP{Server: true}, []exchange{
+			xmpp(xmppServerHeader, xmppStreamReply+xmppFeaturesReply),
+			xmpp(xmppStartTLS, xmppProceedReply),
+		}, "ok"},
+		{"xmpp split replies", XMPP{}, []exchange{
+			xmpp(xmppClientHeader, xmppStreamReply),
+			xmpp("", xmppFeaturesReply),
+			xmpp(xmppStartTLS, xmppProceedReply),
+		}, "ok"},
+		{"xmpp starttls not offered", XMPP{}, []exchange{
+			xmpp(xmppClientHeader, xmppStreamReply+"<stream:features><bind xmlns='urn:ietf:params:xml:ns:xmpp-bind'/></stream:features>"),
+		}, "refused"},
+		{"xmpp failure", XMPP{}, []exchange{
+			xmpp(xmppClientHeader, xmppStreamReply+xmppFeaturesReply),
+			xmpp(xmppStartTLS, "<failure xmlns='urn:ietf:params:xml:ns:xmpp-tls'/></stream:stream>"),
+		}, "refused"},
+		{"xmpp stream error", XMPP{}, []exchange{
+			xmpp(xmppClientHeader, xmppStreamReply+"<stream:error><host-unknown xmlns='urn:ietf:params:xml:ns:xmpp-streams'/></stream:error></stream:stream>"),
+		}, "refused"},
+		{"xmpp not xml", XMPP{}, []exchange{
+			xmpp(xmppClientHeader, "HTTP/1.1 400 Bad Request\r\n\r\n"),
+		}, "error"},
+	})
+}
@@ -0,0 +1,103 @@
+package gojarm
+
+import (
+	"errors"
+
+	"github.com/TheGejr/gojarm/protocols"
+	"github.com/TheGejr/gojarm/scope"
+	"github.com/TheGejr/gojarm/starttls"
+)
+
+// Status describes the outcome of a fingerprint
+type Status string
+
+const (
+	// StatusOK means every probe was sent to the target
+	StatusOK Status = "ok"
+	// StatusClosed means the target did not accept a connection during the pre-check
+	StatusClosed Status = "closed"
+	// StatusNonTLS means the target answered with something other than TLS, see Result.Protocol
+	StatusNonTLS Status = "non-tls"
+	// StatusTLSVersionMismatch means the target speaks TLS, but rejected every probe without a server hello
+	StatusTLSVersionMismatch Status = "tls-version-mismatch"
+	// StatusUpgradeRefused means the server refused to upgrade the connection to TLS
+	StatusUpgradeRefused Status = "upgrade-refused"
+	// StatusUpgradeFailed means the upgrade to TLS failed for another reason, see ProbeResult.Error
+	StatusUpgradeFailed Status = "upgrade-failed"
+	// StatusNegotiationFailure means the server answered the upgrade with a failure PDU, see starttls.NegotiationFailure
+	StatusNegotiationFailure Status = "negotiation-failure"
+)
+
+// preCheck makes a single connection attempt to the target
+//
+// A closed or filtered port results in StatusClosed, while errors are only
+// returned for targets that may not be contacted at all.
+//
+// Connecting a UDP socket sends nothing and always succeeds, so the check is
+// skipped for DTLS and QUIC targets, which only get the first probe check.
+func preCheck(t Target) (Status, error) {
+	if t.Transport == TransportDTLS || t.Transport == TransportQUIC {
+		return StatusOK, nil
+	}
+
+	t.Retries = 0
+	conn, err := dialTarget(t)
+	if errors.Is(err, scope.ErrOutOfScope) {
+		return "", err
+	}
+	if err != nil {
+		return StatusClosed, nil
+	}
+	conn.Close()
+	return StatusOK, nil
+}
+
+// isTLS reports whether a response was sent by a TLS, DTLS or SSL server
+func isTLS(data []byte) bool {
+	return protocols.IsTLSRecord(data) || protocols.IsDTLSRecord(data) || protocols.IsSSLv2(data)
+}
+
+// responseStatus returns the status and detected protocol for a run without a server hello
+func responseStatus(probeResults []ProbeResult) (Status, string) {
+	tls := ""
+	upgrade := Status("")
+	for _, pr := range probeResults {
+		if pr.Raw != "" && pr.Raw != "|||" {
+			return StatusOK, tlsProtocol(pr)
+		}
+		failure := &starttls.NegotiationFailure{}
+		if errors.As(pr.Error, &failure) {
+			upgrade = StatusNegotiationFailure
+		} else if errors.Is(pr.Error, starttls.ErrRefused) {
+			upgrade = StatusUpgradeRefused
+		} else if pr.Error != nil && upgrade == "" {
+			upgrade = StatusUpgradeFailed
+		}
+		if len(pr.Response) == 0 {
+			continue
+		}
+		if pr.Protocol != protocols.QUIC && !isTLS(pr.Response) {
+			return StatusNonTLS, pr.Protocol
+		}
+		tls = tlsProtocol(pr)
+	}
+
+	if upgrade != "" {
+		return upgrade, ""
+	}
+	if tls != "" {
+		return StatusTLSVersionMismatch, tls
+	}
+	return StatusOK, ""
+}
+
+// tlsProtocol returns the protocol of a probe answered with TLS, which is DTLS or QUIC for datagram probes
+func tlsProtocol(pr ProbeResult) string {
+	switch pr.Probe.Transport {
+	case TransportDTLS:
+		return protocols.DTLS
+	case TransportQUIC:
+		return protocols.QUIC
+	}
+	return protocols.TLS
+}
@@ -0,0 +1,88 @@
+package gojarm
+
+import (
+	"net"
+	"testing"
+
+	"github.com/TheGejr/gojarm/jarm"
+)
+
+func TestPreCheck(t *testing.T) {
+	// A listener that is closed right away leaves a port nothing listens on
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	tests := []struct {
+		transport string
+		want      Status
+	}{
+		{"", StatusClosed},
+		{TransportDTLS, StatusOK},
+		{TransportQUIC, StatusOK},
+	}
+
+	for _, tt := range tests {
+		status, err := preCheck(Target{Host: "127.0.0.1", Port: port, Transport: tt.transport})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status != tt.want {
+			t.Errorf("preCheck(%q) = %s, want %s", tt.transport, status, tt.want)
+		}
+	}
+}
+
+func TestPreCheckZeroHash(t *testing.T) {
+	// A TCP port nothing listens on, and a UDP service answering with a banner
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan int)
+	go func() {
+		defer close(done)
+		buff := make([]byte, 65535)
+		for {
+			_, addr, err := conn.ReadFrom(buff)
+			if err != nil {
+				return
+			}
+			conn.WriteTo([]byte("SSH-2.0-OpenSSH_9.6\r\n"), addr)
+		}
+	}()
+	defer func() {
+		conn.Close()
+		<-done
+	}()
+
+	tests := []struct {
+		transport string
+		port      int
+		status    Status
+		hash      string
+	}{
+		{"", closed, StatusClosed, ZeroHash},
+		{TransportDTLS, conn.LocalAddr().(*net.UDPAddr).Port, StatusNonTLS, jarm.DTLSPrefix + ZeroHash},
+	}
+
+	for _, tt := range tests {
+		res := Fingerprint(Target{Host: "127.0.0.1", Port: tt.port, Transport: tt.transport, PreCheck: true})
+		if res.Error != nil {
+			t.Fatal(res.Error)
+		}
+		if res.Status != tt.status || res.Hash != tt.hash {
+			t.Errorf("%q: status %s, hash %s, want %s, %s", tt.transport, res.Status, res.Hash, tt.status, tt.hash)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package gojarm
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/idna"
+
+	"github.com/TheGejr/gojarm/utils"
+)
+
+// DefaultPort is used for targets that do not specify a port
+const DefaultPort = 443
+
+// schemePorts holds the default ports of URL schemes
+var schemePorts = map[string]int{
+	"https": 443,
+	"wss":   443,
+	"smtps": 465,
+	"ldaps": 636,
+	"ftps":  990,
+	"imaps": 993,
+	"pop3s": 995,
+}
+
+// targetSpec is a single parsed target specification
+type targetSpec struct {
+	hosts []string
+	cidr  string
+	first netip.Addr
+	last  netip.Addr
+	ports []int
+}
+
+// ParsePorts parses a list of ports and port ranges, such as "443,8443,9000-9010"
+func ParsePorts(spec string) ([]int, error) {
+	ports := []int{}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		from, to, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(from)
+		if err != nil || !utils.ValidPort(start) {
+			return nil, fmt.Errorf("invalid port: %s", from)
+		}
+		end := start
+		if isRange {
+			end, err = strconv.Atoi(to)
+			if err != nil || !utils.ValidPort(end) || end < start {
+				return nil, fmt.Errorf("invalid port range: %s", part)
+			}
+		}
+
+		for p := start; p <= end; p++ {
+			ports = append(ports, p)
+		}
+	}
+
+	if len(ports) == 0 {
+		return nil, fmt.Errorf("invalid port list: %q", spec)
+	}
+	return ports, nil
+}
+
+// ExpandTargets parses target specifications and writes the resulting targets to a channel
+//
+// A specification is a host, host:port, [v6]:port, URL, CIDR or a dash range
+// of addresses such as 10.0.0.1-10.0.0.50 or 10.0.0.1-50. Specifications
+// without a port are expanded with every port of the ports list, which
+// defaults to DefaultPort when empty. Hostnames are converted to punycode.
+// IPv6 dash ranges may hold at most utils.MaxIPv6Addresses addresses, larger
+// networks have to be given as a CIDR, which is sampled instead.
+// Every target is a copy of the template with Host and Port filled in.
+//
+// All specifications are validated before any target is written.
+func ExpandTargets(specs []string, ports string, template Target, out chan Target, quit chan int) error {
+	defaultPorts := []int{DefaultPort}
+	if strings.TrimSpace(ports) != "" {
+		p, err := ParsePorts(ports)
+		if err != nil {
+			return err
+		}
+		defaultPorts = p
+	}
+
+	parsed := []targetSpec{}
+	for _, s := range specs {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		ts, err := parseTargetSpec(s, defaultPorts)
+		if err != nil {
+			return err
+		}
+		parsed = append(parsed, ts)
+	}
+
+	emit := func(host string, ports []int) bool {
+		for _, p := range ports {
+			t := template
+			t.Host = host
+			t.Port = p
+			select {
+			case <-quit:
+				return false
+			case out <- t:
+			}
+		}
+		return true
+	}
+
+	for _, ts := range parsed {
+		switch {
+		case ts.cidr != "":
+			if !expandCIDR(ts, emit, quit) {
+				return nil
+			}
+		case ts.first.IsValid():
+			for a := ts.first; a.IsValid() && a.Compare(ts.last) <= 0; a = a.Next() {
+				if !emit(a.String(), ts.ports) {
+					return nil
+				}
+			}
+		default:
+			for _, h := range ts.hosts {
+				if !emit(h, ts.ports) {
+					return nil
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// expandCIDR emits every address of a CIDR specification, returning false if quit was signaled
+func expandCIDR(ts targetSpec, emit func(string, []int) bool, quit chan int) bool {
+	addrs := make(chan string)
+	stop := make(chan int)
+	go func() {
+		utils.AddressesFromCIDR(ts.cidr, addrs, stop)
+		close(addrs)
+	}()
+
+	for a := range addrs {
+		if !emit(a, ts.ports) {
+			close(stop)
+			return false
+		}
+	}
+	return true
+}
+
+// parseTargetSpec parses and validates a single target specification
+func parseTargetSpec(s string, defaultPorts []int) (targetSpec, error) {
+	ts := targetSpec{ports: defaultPorts}
+
+	// URLs
+	if strings.Contains(s, "://") {
+		u, err := url.Parse(s)
+		if err != nil {
+			return ts, fmt.Errorf("invalid target URL: %s", s)
+		}
+		host, err := normalizeHost(u.Hostname())
+		if err != nil {
+			return ts, err
+		}
+		ts.hosts = []string{host}
+
+		if u.Port() != "" {
+			port, err := strconv.Atoi(u.Port())
+			if err != nil || !utils.ValidPort(port) {
+				return ts, fmt.Errorf("invalid target port: %s", s)
+			}
+			ts.ports = []int{port}
+		} else if port, ok := schemePorts[strings.ToLower(u.Scheme)]; ok {
+			ts.ports = []int{port}
+		}
+		return ts, nil
+	}
+
+	// CIDRs
+	if strings.Contains(s, "/") {
+		if _, err := netip.ParsePrefix(s); err != nil {
+			return ts, fmt.Errorf("invalid CIDR: %s", s)
+		}
+		ts.cidr = s
+		return ts, nil
+	}
+
+	// Dash ranges, hostnames may contain dashes so the start has to be an address
+	if from, to, ok := strings.Cut(s, "-"); ok {
+		if first, err := netip.ParseAddr(from); err == nil {
+			last, err := parseRangeEnd(first, to)
+			if err != nil {
+				return ts, err
+			}
+			if last.Compare(first) < 0 {
+				return ts, fmt.Errorf("invalid address range: %s", s)
+			}
+			if first.Is6() && rangeSize(first, last).Cmp(new(big.Int).SetUint64(utils.MaxIPv6Addresses)) > 0 {
+				return ts, fmt.Errorf("address range too large: %s (more than %d addresses)", s, utils.MaxIPv6Addresses)
+			}
+			ts.first = first
+			ts.last = last
+			return ts, nil
+		}
+	}
+
+	// Bare IPv6 addresses, which can not carry a port without brackets
+	if addr, err := netip.ParseAddr(s); err == nil {
+		ts.hosts = []string{addr.String()}
+		return ts, nil
+	}
+
+	host := s
+	if h, p, err := net.SplitHostPort(s); err == nil {
+		port, err := strconv.Atoi(p)
+		if err != nil || !utils.ValidPort(port) {
+			return ts, fmt.Errorf("invalid target port: %s", s)
+		}
+		host = h
+		ts.ports = []int{port}
+	}
+
+	host, err := normalizeHost(strings.Trim(host, "[]"))
+	if err != nil {
+		return ts, err
+	}
+	ts.hosts = []string{host}
+	return ts, nil
+}
+
+// rangeSize returns the number of addresses from first to last, inclusive
+func rangeSize(first, last netip.Addr) *big.Int {
+	a, b := first.As16(), last.As16()
+	size := new(big.Int).Sub(new(big.Int).SetBytes(b[:]), new(big.Int).SetBytes(a[:]))
+	return size.Add(size, big.NewInt(1))
+}
+
+// parseRangeEnd parses the end of a dash range, which is either a full address or the last octet
+func parseRangeEnd(first netip.Addr, to string) (netip.Addr, error) {
+	if last, err := netip.ParseAddr(to); err == nil {
+		if last.Is4() != first.Is4() {
+			return last, fmt.Errorf("invalid address range: mixed address families")
+		}
+		return last, nil
+	}
+
+	octet, err := strconv.Atoi(to)
+	if err != nil || !first.Is4() || octet < 0 || octet > 255 {
+		return netip.Addr{}, fmt.Errorf("invalid address range end: %s", to)
+	}
+	last := first.As4()
+	last[3] = byte(octet)
+	return netip.AddrFrom4(last), nil
+}
+
+// normalizeHost validates a host and converts hostnames to punycode
+func normalizeHost(host string) (string, error) {
+	if host == "" {
+		return "", fmt.Errorf("invalid target: empty host")
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return addr.String(), nil
+	}
+
+	ascii, err := idna.Lookup.ToASCII(host)
+	if err != nil {
+		return "", fmt.Errorf("invalid target host: %s %s", host, err)
+	}
+	return ascii, nil
+}
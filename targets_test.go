@@ -0,0 +1,140 @@
+package gojarm
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestIPv6RangeLimit(t *testing.T) {
+	tests := []struct {
+		spec string
+		ok   bool
+	}{
+		{"2001:db8::-2001:db8::ffff", true},
+		{"2001:db8::-2001:db8::ff:ffff", true}, // utils.MaxIPv6Addresses
+		{"2001:db8::-2001:db8::100:0", false},
+		{"2001:db8::-2001:db8::ffff:ffff:ffff", false},
+		{"::-ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff", false},
+		{"10.0.0.0-10.255.255.255", true},
+		{"1.0.0.0-2.255.255.255", true},
+	}
+
+	for _, tt := range tests {
+		_, err := parseTargetSpec(tt.spec, []int{443})
+		if (err == nil) != tt.ok {
+			t.Errorf("parseTargetSpec(%s): err = %v", tt.spec, err)
+		}
+		if err != nil && !strings.Contains(err.Error(), "too large") {
+			t.Errorf("parseTargetSpec(%s): unexpected error: %s", tt.spec, err)
+		}
+	}
+}
+
+// expand runs ExpandTargets and returns the targets as host:port strings
+func expand(specs []string, ports string) ([]string, error) {
+	out := make(chan Target)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- ExpandTargets(specs, ports, Target{}, out, make(chan int))
+		close(out)
+	}()
+
+	targets := []string{}
+	for t := range out {
+		targets = append(targets, net.JoinHostPort(t.Host, strconv.Itoa(t.Port)))
+	}
+	return targets, <-errs
+}
+
+func TestExpandTargets(t *testing.T) {
+	tests := []struct {
+		specs []string
+		ports string
+		want  []string
+	}{
+		{[]string{"example.com"}, "", []string{"example.com:443"}},
+		{[]string{" example.com:8443 ", ""}, "", []string{"example.com:8443"}},
+		{[]string{"example.com"}, "443,8000-8001", []string{"example.com:443", "example.com:8000", "example.com:8001"}},
+		{[]string{"10.0.0.1-3"}, "", []string{"10.0.0.1:443", "10.0.0.2:443", "10.0.0.3:443"}},
+		{[]string{"10.0.0.255-10.0.1.0"}, "", []string{"10.0.0.255:443", "10.0.1.0:443"}},
+		{[]string{"2001:db8::1-2001:db8::2"}, "", []string{"[2001:db8::1]:443", "[2001:db8::2]:443"}},
+		{[]string{"2001:DB8::1"}, "", []string{"[2001:db8::1]:443"}},
+		{[]string{"[2001:db8::1]:8443"}, "", []string{"[2001:db8::1]:8443"}},
+		{[]string{"https://example.com/path"}, "25", []string{"example.com:443"}},
+		{[]string{"smtps://mail.example.com"}, "", []string{"mail.example.com:465"}},
+		{[]string{"https://example.com:9443"}, "", []string{"example.com:9443"}},
+		{[]string{"gopher://example.com"}, "70", []string{"example.com:70"}},
+		{[]string{"bücher.example"}, "", []string{"xn--bcher-kva.example:443"}},
+		{[]string{"my-host.example.com"}, "", []string{"my-host.example.com:443"}},
+		{[]string{"10.0.0.0/30"}, "", []string{"10.0.0.0:443", "10.0.0.1:443", "10.0.0.2:443", "10.0.0.3:443"}},
+	}
+
+	for _, tt := range tests {
+		got, err := expand(tt.specs, tt.ports)
+		if err != nil {
+			t.Errorf("ExpandTargets(%q, %q): %s", tt.specs, tt.ports, err)
+			continue
+		}
+		// CIDRs are walked in a pseudo-random order
+		sort.Strings(got)
+		want := append([]string{}, tt.want...)
+		sort.Strings(want)
+		if strings.Join(got, " ") != strings.Join(want, " ") {
+			t.Errorf("ExpandTargets(%q, %q) = %v, want %v", tt.specs, tt.ports, got, want)
+		}
+	}
+}
+
+func TestExpandTargetsInvalid(t *testing.T) {
+	tests := []struct {
+		specs []string
+		ports string
+	}{
+		{[]string{"example.com:0"}, ""},
+		{[]string{"example.com:70000"}, ""},
+		{[]string{"https://example.com:http"}, ""},
+		{[]string{"10.0.0.0/33"}, ""},
+		{[]string{"10.0.0.5-1"}, ""},
+		{[]string{"10.0.0.1-256"}, ""},
+		{[]string{"10.0.0.1-2001:db8::1"}, ""},
+		{[]string{"2001:db8::1-5"}, ""},
+		{[]string{"[]:443"}, ""},
+		{[]string{"example.com"}, "443,x"},
+		{[]string{"example.com"}, "9000-8000"},
+		{[]string{"example.com"}, ","},
+		// Nothing is written if any specification is invalid
+		{[]string{"example.com", "example.org:99999"}, ""},
+	}
+
+	for _, tt := range tests {
+		got, err := expand(tt.specs, tt.ports)
+		if err == nil {
+			t.Errorf("ExpandTargets(%q, %q) accepted an invalid specification", tt.specs, tt.ports)
+		}
+		if len(got) > 0 {
+			t.Errorf("ExpandTargets(%q, %q) wrote %v", tt.specs, tt.ports, got)
+		}
+	}
+}
+
+func TestParsePorts(t *testing.T) {
+	tests := []struct {
+		spec string
+		want []int
+	}{
+		{"443", []int{443}},
+		{" 443 , 8443 ", []int{443, 8443}},
+		{"1-3,5", []int{1, 2, 3, 5}},
+		{"65535", []int{65535}},
+	}
+	for _, tt := range tests {
+		got, err := ParsePorts(tt.spec)
+		if err != nil || fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("ParsePorts(%q) = %v, %v, want %v", tt.spec, got, err, tt.want)
+		}
+	}
+}
@@ -4,11 +4,8 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
-	"math"
-	"math/big"
 	"math/rand"
 	"net"
-	"strings"
 )
 
 /*
@@ -55,79 +52,16 @@ func AddressesFromCIDR(cidr string, out chan string, quit chan int) error {
 		return fmt.Errorf("invalid CIDR: empty")
 	}
 
-	// We may receive bare IP addresses, add a mask if needed
-	if !strings.Contains(cidr, "/") {
-		if strings.Contains(cidr, ":") {
-			cidr = cidr + "/128"
-		} else {
-			cidr = cidr + "/32"
-		}
-	}
-
-	// Parse CIDR into base address + mask
-	_, net, err := net.ParseCIDR(cidr)
-	if err != nil {
-		return fmt.Errorf("invalid CIDR: %s %s", cidr, err.Error())
-	}
-
-	// Verify IPv4 for now
-	ip4 := net.IP.To4()
-	if ip4 == nil {
-		return fmt.Errorf("invalid IPv4 CIDR: %s", cidr)
-	}
-
-	netBase, err := IPv42UInt(net.IP.String())
+	// Iterate the range semi-randomly
+	p, err := NewPermutation(cidr, rand.Int63())
 	if err != nil {
-		return fmt.Errorf("invalid IPv4: %s %s", cidr, err)
+		return err
 	}
-
-	maskOnes, maskTotal := net.Mask.Size()
-
-	// Does not work for IPv6 due to cast to uint32
-	netSize := uint32(math.Pow(2, float64(maskTotal-maskOnes)))
-	curBase := netBase
-	endBase := netBase + netSize
-
-	// Iterate the range semi-randomly
-	randomWalkIPv4Range(curBase, endBase, out, quit)
+	p.Walk(out, quit)
 
 	return nil
 }
 
-// findPrimeOverMin returns a prime int64 of at least min
-func findPrimeOverMin(min int64) int64 {
-	var randomSeed int64
-	for i := 0; ; i++ {
-		randomSeed = rand.Int63()
-		// ProbablyPrime is 100% accurate for inputs less than 2⁶⁴
-		if big.NewInt(randomSeed).ProbablyPrime(1) {
-			if randomSeed > min {
-				return randomSeed
-			}
-		}
-	}
-}
-
-// randomWalkIPv4Range iterates over an IPv4 range using a prime, writing IPs to the output channel
-func randomWalkIPv4Range(min uint32, max uint32, out chan string, quit chan int) {
-	s := int64(max - min)
-	p := findPrimeOverMin(int64(s))
-	if s == 0 {
-		return
-	}
-
-	q := p % s
-	for v := int64(0); v < s; v++ {
-		ip := UInt2IPv4(min + uint32(q))
-		select {
-		case <-quit:
-			return
-		case out <- ip:
-			q = (q + p) % s
-		}
-	}
-}
-
 // ValidPort determines if a port number is valid
 func ValidPort(port int) (valid bool) {
 	if port < 1 || port > 65535 {
@@ -0,0 +1,257 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"net/netip"
+	"os"
+	"strings"
+	"sync"
+)
+
+// MaxIPv6Addresses caps the number of addresses produced for a single IPv6 CIDR
+//
+// Larger prefixes, such as a /64, are sampled by stopping the pseudo-random walk after this many addresses.
+var MaxIPv6Addresses uint64 = 1 << 24
+
+// WalkState is the serializable state of a Permutation
+//
+// The walk visits Base + (Prime * (i+1)) mod Size for every index i below
+// Limit. The prime is derived from Seed and is larger than, and thereby
+// coprime with, Size, so every address of the range is visited exactly once.
+// Index is the index of the next address to hand out.
+//
+// A sharded walk only visits the indices i where i mod Shards equals Shard.
+type WalkState struct {
+	Base   string   `json:"base"`
+	Size   *big.Int `json:"size"`
+	Seed   int64    `json:"seed"`
+	Prime  *big.Int `json:"prime"`
+	Index  uint64   `json:"index"`
+	Limit  uint64   `json:"limit"`
+	Shard  uint64   `json:"shard,omitempty"`
+	Shards uint64   `json:"shards,omitempty"`
+}
+
+// Permutation is a resumable pseudo-random walk over the addresses of a CIDR
+type Permutation struct {
+	mu    sync.Mutex
+	state WalkState
+	base  *big.Int
+	is4   bool
+}
+
+// NewPermutation returns a walk over the CIDR, with the order determined by the seed
+func NewPermutation(cidr string, seed int64) (*Permutation, error) {
+	// We may receive bare IP addresses, add a mask if needed
+	if !strings.Contains(cidr, "/") {
+		if strings.Contains(cidr, ":") {
+			cidr = cidr + "/128"
+		} else {
+			cidr = cidr + "/32"
+		}
+	}
+
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CIDR: %s %s", cidr, err.Error())
+	}
+	if prefix.Addr().Is4In6() {
+		return nil, fmt.Errorf("invalid CIDR: %s", cidr)
+	}
+	prefix = prefix.Masked()
+
+	size := new(big.Int).Lsh(big.NewInt(1), uint(prefix.Addr().BitLen()-prefix.Bits()))
+	limit := MaxIPv6Addresses
+	if size.IsUint64() && (prefix.Addr().Is4() || size.Uint64() < limit) {
+		limit = size.Uint64()
+	}
+
+	return ResumePermutation(WalkState{
+		Base:  prefix.Addr().String(),
+		Size:  size,
+		Seed:  seed,
+		Prime: primeOver(size, seed),
+		Limit: limit,
+	})
+}
+
+// ResumePermutation continues a walk from a saved state
+func ResumePermutation(state WalkState) (*Permutation, error) {
+	base, err := netip.ParseAddr(state.Base)
+	if err != nil {
+		return nil, fmt.Errorf("invalid walk base: %s", state.Base)
+	}
+	if state.Size == nil || state.Size.Sign() <= 0 || state.Prime == nil {
+		return nil, fmt.Errorf("invalid walk state")
+	}
+	if state.Prime.Cmp(state.Size) <= 0 || !state.Prime.ProbablyPrime(20) {
+		return nil, fmt.Errorf("invalid walk prime: %s", state.Prime)
+	}
+	if state.Shards > 0 && (state.Shard >= state.Shards || state.Index%state.Shards != state.Shard) {
+		return nil, fmt.Errorf("invalid walk shard: %d of %d at index %d", state.Shard, state.Shards, state.Index)
+	}
+
+	raw := base.AsSlice()
+	return &Permutation{
+		state: state,
+		base:  new(big.Int).SetBytes(raw),
+		is4:   base.Is4(),
+	}, nil
+}
+
+// LoadPermutation resumes a walk from a checkpoint written by Save
+func LoadPermutation(path string) (*Permutation, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	state := WalkState{}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	return ResumePermutation(state)
+}
+
+// SetShard restricts the walk to shard i of n
+//
+// Workers sharing the CIDR and seed, each with its own shard, together
+// visit every address of the walk exactly once. The shard has to be set
+// before the walk is started.
+func (p *Permutation) SetShard(i, n uint64) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if n == 0 || i >= n {
+		return fmt.Errorf("invalid shard: %d of %d", i, n)
+	}
+	if p.state.Index != p.state.Shard {
+		return fmt.Errorf("walk already started")
+	}
+
+	p.state.Shard = i
+	p.state.Shards = n
+	p.state.Index = i
+	return nil
+}
+
+// State returns a snapshot of the walk state
+func (p *Permutation) State() WalkState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	state := p.state
+	state.Size = new(big.Int).Set(p.state.Size)
+	state.Prime = new(big.Int).Set(p.state.Prime)
+	return state
+}
+
+// Save writes a checkpoint of the walk to a file
+//
+// The file is replaced atomically, so a crash while saving leaves the previous checkpoint intact.
+func (p *Permutation) Save(path string) error {
+	data, err := json.Marshal(p.State())
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Next returns the next address of the walk, or false when the walk is done
+func (p *Permutation) Next() (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.state.Index >= p.state.Limit {
+		return "", false
+	}
+
+	ip := p.addressAt(p.state.Index)
+	p.advance()
+	return ip, true
+}
+
+// Walk writes the remaining addresses of the walk to the output channel
+//
+// An address only counts as handed out once it has been received, so a
+// checkpoint taken while walking never skips addresses.
+func (p *Permutation) Walk(out chan string, quit chan int) {
+	for {
+		p.mu.Lock()
+		if p.state.Index >= p.state.Limit {
+			p.mu.Unlock()
+			return
+		}
+		ip := p.addressAt(p.state.Index)
+		p.mu.Unlock()
+
+		select {
+		case <-quit:
+			return
+		case out <- ip:
+			p.mu.Lock()
+			p.advance()
+			p.mu.Unlock()
+		}
+	}
+}
+
+// advance moves the walk to the next index of its shard
+func (p *Permutation) advance() {
+	if p.state.Shards > 0 {
+		p.state.Index += p.state.Shards
+		return
+	}
+	p.state.Index++
+}
+
+// addressAt returns the address visited at the given index
+func (p *Permutation) addressAt(index uint64) string {
+	q := new(big.Int).SetUint64(index)
+	q.Add(q, big.NewInt(1)).Mul(q, p.state.Prime).Mod(q, p.state.Size).Add(q, p.base)
+
+	if p.is4 {
+		ip := [4]byte{}
+		q.FillBytes(ip[:])
+		return netip.AddrFrom4(ip).String()
+	}
+	ip := [16]byte{}
+	q.FillBytes(ip[:])
+	return netip.AddrFrom16(ip).String()
+}
+
+// primeOver returns a prime larger than min, chosen pseudo-randomly from the seed
+func primeOver(min *big.Int, seed int64) *big.Int {
+	rnd := rand.New(rand.NewSource(seed))
+	bits := min.BitLen() + 1
+	if bits < 63 {
+		bits = 63
+	}
+
+	// Steps of 1 or -1 modulo the range would walk it sequentially, ranges
+	// larger than 6 always have other steps coprime with their size
+	one := big.NewInt(1)
+	last := new(big.Int).Sub(min, one)
+
+	limit := new(big.Int).Lsh(one, uint(bits))
+	step := new(big.Int)
+	for {
+		candidate := new(big.Int).Rand(rnd, limit)
+		if candidate.Cmp(min) <= 0 || !candidate.ProbablyPrime(20) {
+			continue
+		}
+		step.Mod(candidate, min)
+		if min.Cmp(big.NewInt(6)) > 0 && (step.Cmp(one) == 0 || step.Cmp(last) == 0) {
+			continue
+		}
+		return candidate
+	}
+}
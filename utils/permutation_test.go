@@ -0,0 +1,295 @@
+package utils
+
+import (
+	"math/big"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fixedWalk visits 10.0.0.0/28 with a step of 23 mod 16 = 7
+func fixedWalk() WalkState {
+	return WalkState{
+		Base:  "10.0.0.0",
+		Size:  big.NewInt(16),
+		Seed:  1,
+		Prime: big.NewInt(23),
+		Limit: 16,
+	}
+}
+
+var fixedOrder = []string{
+	"10.0.0.7", "10.0.0.14", "10.0.0.5", "10.0.0.12", "10.0.0.3", "10.0.0.10", "10.0.0.1", "10.0.0.8",
+	"10.0.0.15", "10.0.0.6", "10.0.0.13", "10.0.0.4", "10.0.0.11", "10.0.0.2", "10.0.0.9", "10.0.0.0",
+}
+
+// drain returns the remaining addresses of a walk
+func drain(p *Permutation) []string {
+	addrs := []string{}
+	for {
+		a, ok := p.Next()
+		if !ok {
+			return addrs
+		}
+		addrs = append(addrs, a)
+	}
+}
+
+func TestPermutationFixedState(t *testing.T) {
+	p, err := ResumePermutation(fixedWalk())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(drain(p), " "); got != strings.Join(fixedOrder, " ") {
+		t.Errorf("walk = %s", got)
+	}
+}
+
+func TestPermutationCoversCIDR(t *testing.T) {
+	tests := []struct {
+		cidr  string
+		count int
+	}{
+		{"192.0.2.0/24", 256},
+		{"192.0.2.7", 1},
+		{"198.51.100.0/31", 2},
+		{"2001:db8::/120", 256},
+		{"2001:db8::1", 1},
+	}
+
+	for _, tt := range tests {
+		p, err := NewPermutation(tt.cidr, 42)
+		if err != nil {
+			t.Fatal(err)
+		}
+		addrs := drain(p)
+		seen := map[string]bool{}
+		for _, a := range addrs {
+			seen[a] = true
+		}
+		if len(addrs) != tt.count || len(seen) != tt.count {
+			t.Errorf("%s: %d addresses, %d unique, want %d", tt.cidr, len(addrs), len(seen), tt.count)
+		}
+
+		// The same seed gives the same order
+		again, _ := NewPermutation(tt.cidr, 42)
+		if strings.Join(drain(again), " ") != strings.Join(addrs, " ") {
+			t.Errorf("%s: walk is not deterministic", tt.cidr)
+		}
+	}
+
+	// Large IPv6 prefixes are sampled
+	p, err := NewPermutation("2001:db8::/64", 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limit := p.State().Limit; limit != MaxIPv6Addresses {
+		t.Errorf("/64 limit = %d, want %d", limit, MaxIPv6Addresses)
+	}
+
+	for _, cidr := range []string{"", "10.0.0.0/33", "::ffff:10.0.0.0/120", "example.com/24"} {
+		if _, err := NewPermutation(cidr, 1); err == nil {
+			t.Errorf("NewPermutation(%q) accepted an invalid CIDR", cidr)
+		}
+	}
+}
+
+func TestPermutationResume(t *testing.T) {
+	p, err := NewPermutation("192.0.2.0/26", 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	full := drain(p)
+
+	p, _ = NewPermutation("192.0.2.0/26", 7)
+	first := []string{}
+	for i := 0; i < 20; i++ {
+		a, _ := p.Next()
+		first = append(first, a)
+	}
+
+	// Resume from a state snapshot and from a checkpoint file
+	resumed, err := ResumePermutation(p.State())
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "walk.json")
+	if err := p.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadPermutation(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := strings.Join(full[20:], " ")
+	if got := strings.Join(drain(resumed), " "); got != want {
+		t.Errorf("resumed walk = %s, want %s", got, want)
+	}
+	if got := strings.Join(drain(loaded), " "); got != want {
+		t.Errorf("loaded walk = %s, want %s", got, want)
+	}
+	if strings.Join(first, " ") != strings.Join(full[:20], " ") {
+		t.Errorf("walk before the checkpoint diverged")
+	}
+}
+
+func TestPermutationWalkCheckpoint(t *testing.T) {
+	p, err := ResumePermutation(fixedWalk())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := make(chan string)
+	quit := make(chan int)
+	done := make(chan int)
+	go func() {
+		p.Walk(out, quit)
+		close(done)
+	}()
+
+	for i := 0; i < 5; i++ {
+		if a := <-out; a != fixedOrder[i] {
+			t.Fatalf("address %d = %s, want %s", i, a, fixedOrder[i])
+		}
+	}
+	close(quit)
+	<-done
+
+	// The address offered but never received is handed out again
+	if index := p.State().Index; index != 5 {
+		t.Errorf("index after quit = %d, want 5", index)
+	}
+	if a, _ := p.Next(); a != fixedOrder[5] {
+		t.Errorf("next address = %s, want %s", a, fixedOrder[5])
+	}
+}
+
+func TestResumePermutationInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*WalkState)
+	}{
+		{"base", func(s *WalkState) { s.Base = "10.0.0" }},
+		{"no size", func(s *WalkState) { s.Size = nil }},
+		{"zero size", func(s *WalkState) { s.Size = big.NewInt(0) }},
+		{"no prime", func(s *WalkState) { s.Prime = nil }},
+		{"prime below size", func(s *WalkState) { s.Prime = big.NewInt(13) }},
+		{"not a prime", func(s *WalkState) { s.Prime = big.NewInt(21) }},
+	}
+
+	for _, tt := range tests {
+		state := fixedWalk()
+		tt.modify(&state)
+		if _, err := ResumePermutation(state); err == nil {
+			t.Errorf("%s: invalid state accepted", tt.name)
+		}
+	}
+}
+
+func TestPermutationShardFixedState(t *testing.T) {
+	p, err := ResumePermutation(fixedWalk())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.SetShard(1, 4); err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join([]string{fixedOrder[1], fixedOrder[5], fixedOrder[9], fixedOrder[13]}, " ")
+	if got := strings.Join(drain(p), " "); got != want {
+		t.Errorf("shard 1 of 4 = %s, want %s", got, want)
+	}
+}
+
+func TestPermutationShardsCoverCIDR(t *testing.T) {
+	tests := []struct {
+		cidr   string
+		shards uint64
+	}{
+		{"192.0.2.0/24", 1},
+		{"192.0.2.0/24", 3},
+		{"192.0.2.0/24", 7},
+		{"192.0.2.0/30", 8},
+		{"2001:db8::/120", 5},
+	}
+
+	for _, tt := range tests {
+		p, _ := NewPermutation(tt.cidr, 99)
+		full := drain(p)
+
+		seen := map[string]int{}
+		for i := uint64(0); i < tt.shards; i++ {
+			p, err := NewPermutation(tt.cidr, 99)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := p.SetShard(i, tt.shards); err != nil {
+				t.Fatal(err)
+			}
+
+			// Checkpoint every shard halfway through
+			for j := 0; j < 10; j++ {
+				a, ok := p.Next()
+				if !ok {
+					break
+				}
+				seen[a]++
+			}
+			resumed, err := ResumePermutation(p.State())
+			if err != nil {
+				t.Fatalf("%s shard %d: %s", tt.cidr, i, err)
+			}
+			for _, a := range drain(resumed) {
+				seen[a]++
+			}
+		}
+
+		if len(seen) != len(full) {
+			t.Errorf("%s/%d: shards visited %d addresses, want %d", tt.cidr, tt.shards, len(seen), len(full))
+		}
+		for a, n := range seen {
+			if n != 1 {
+				t.Errorf("%s/%d: %s visited %d times", tt.cidr, tt.shards, a, n)
+			}
+		}
+	}
+}
+
+func TestPermutationShardInvalid(t *testing.T) {
+	tests := []struct {
+		shard, shards uint64
+	}{
+		{0, 0},
+		{1, 1},
+		{4, 4},
+		{5, 4},
+	}
+	for _, tt := range tests {
+		p, _ := ResumePermutation(fixedWalk())
+		if err := p.SetShard(tt.shard, tt.shards); err == nil {
+			t.Errorf("SetShard(%d, %d) accepted", tt.shard, tt.shards)
+		}
+	}
+
+	// Sharding a walk that already started would skip or repeat addresses
+	p, _ := ResumePermutation(fixedWalk())
+	p.Next()
+	if err := p.SetShard(0, 2); err == nil {
+		t.Errorf("SetShard accepted after the walk started")
+	}
+
+	states := []struct {
+		name   string
+		modify func(*WalkState)
+	}{
+		{"shard out of range", func(s *WalkState) { s.Shard, s.Shards = 2, 2 }},
+		{"index of another shard", func(s *WalkState) { s.Shard, s.Shards, s.Index = 1, 4, 6 }},
+	}
+	for _, tt := range states {
+		state := fixedWalk()
+		tt.modify(&state)
+		if _, err := ResumePermutation(state); err == nil {
+			t.Errorf("%s: invalid state accepted", tt.name)
+		}
+	}
+}